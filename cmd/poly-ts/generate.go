@@ -0,0 +1,706 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const polyPath = "github.com/ykalchevskiy/poly"
+
+// alias is a Poly alias declared in the package.
+type alias struct {
+	name     string
+	polyName string // local name of the poly package in the file of the alias
+	types    ast.Expr
+}
+
+// pkg holds the declarations of a parsed package needed to generate TypeScript.
+type pkg struct {
+	specs     map[string]*ast.TypeSpec
+	typeNames map[string]string // Go type name -> TypeName
+	versions  map[string]int    // Go type name -> PolyVersion
+	consts    map[string]string // string constants
+	aliases   []alias
+}
+
+type generator struct {
+	pkg      *pkg
+	buf      bytes.Buffer
+	variants map[string][]string // alias name -> variant names
+	emitted  map[string]bool
+	guarded  map[string]bool // variants whose guards are emitted, as aliases can share variants
+	queue    []string
+}
+
+func generate(dir string, guards bool) ([]byte, error) {
+	p, err := parsePackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.aliases) == 0 {
+		return nil, fmt.Errorf("no poly.Poly aliases found in %s", dir)
+	}
+
+	g := &generator{
+		pkg:      p,
+		variants: make(map[string][]string),
+		emitted:  make(map[string]bool),
+		guarded:  make(map[string]bool),
+	}
+
+	for _, a := range p.aliases {
+		names, err := variants(a.types, a.polyName)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a.name, err)
+		}
+
+		g.variants[a.name] = names
+	}
+
+	g.buf.WriteString("// Code generated by poly-ts. DO NOT EDIT.\n")
+
+	for _, a := range p.aliases {
+		if err := g.union(a.name, guards); err != nil {
+			return nil, err
+		}
+	}
+
+	for len(g.queue) > 0 {
+		name := g.queue[0]
+		g.queue = g.queue[1:]
+
+		if err := g.object(name, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.buf.Bytes(), nil
+}
+
+// parsePackage parses the files of the package in dir selected by the build constraints, excluding tests.
+func parsePackage(dir string) (*pkg, error) {
+	fset := token.NewFileSet()
+
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := append(append([]string(nil), buildPkg.GoFiles...), buildPkg.CgoFiles...)
+	sort.Strings(paths)
+
+	p := &pkg{
+		specs:     make(map[string]*ast.TypeSpec),
+		typeNames: make(map[string]string),
+		versions:  make(map[string]int),
+		consts:    make(map[string]string),
+	}
+
+	var methods []*ast.FuncDecl
+
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, path), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		polyName := importName(file)
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv != nil && (decl.Name.Name == "TypeName" || decl.Name.Name == "PolyVersion") {
+					methods = append(methods, decl)
+				}
+			case *ast.GenDecl:
+				p.addGenDecl(decl, polyName)
+			}
+		}
+	}
+
+	for _, method := range methods {
+		add := p.addTypeName
+		if method.Name.Name == "PolyVersion" {
+			add = p.addVersion
+		}
+
+		if err := add(method); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// importName returns the local name of the poly package in file, or "" if it is not imported.
+func importName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path != polyPath {
+			continue
+		}
+
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+
+		return "poly"
+	}
+
+	return ""
+}
+
+func (p *pkg) addGenDecl(decl *ast.GenDecl, polyName string) {
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			p.specs[spec.Name.Name] = spec
+
+			if spec.Assign == token.NoPos || polyName == "" {
+				continue
+			}
+
+			index, ok := spec.Type.(*ast.IndexListExpr)
+			if !ok {
+				continue
+			}
+
+			if name, ok := polySelector(index.X, polyName); ok && name == "Poly" {
+				p.aliases = append(p.aliases, alias{
+					name:     spec.Name.Name,
+					polyName: polyName,
+					types:    index.Indices[1],
+				})
+			}
+		case *ast.ValueSpec:
+			if decl.Tok != token.CONST {
+				continue
+			}
+
+			for i, name := range spec.Names {
+				if i >= len(spec.Values) {
+					break
+				}
+
+				if lit, ok := spec.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					if value, err := strconv.Unquote(lit.Value); err == nil {
+						p.consts[name.Name] = value
+					}
+				}
+			}
+		}
+	}
+}
+
+// addTypeName records the TypeName returned by method, which must be a single return
+// of a string literal or a string constant.
+func (p *pkg) addTypeName(method *ast.FuncDecl) error {
+	recv := method.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	if method.Body == nil || len(method.Body.List) != 1 {
+		return fmt.Errorf("TypeName of %s must be a single return statement", ident.Name)
+	}
+
+	ret, ok := method.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return fmt.Errorf("TypeName of %s must be a single return statement", ident.Name)
+	}
+
+	switch result := ret.Results[0].(type) {
+	case *ast.BasicLit:
+		value, err := strconv.Unquote(result.Value)
+		if err != nil || result.Kind != token.STRING {
+			return fmt.Errorf("TypeName of %s must return a string", ident.Name)
+		}
+
+		p.typeNames[ident.Name] = value
+	case *ast.Ident:
+		value, ok := p.consts[result.Name]
+		if !ok {
+			return fmt.Errorf("TypeName of %s must return a string constant", ident.Name)
+		}
+
+		p.typeNames[ident.Name] = value
+	default:
+		return fmt.Errorf("TypeName of %s must return a string literal or constant", ident.Name)
+	}
+
+	return nil
+}
+
+// addVersion records the version returned by the PolyVersion method, which must be a single return of an integer literal.
+func (p *pkg) addVersion(method *ast.FuncDecl) error {
+	recv := method.Recv.List[0].Type
+	if star, ok := recv.(*ast.StarExpr); ok {
+		recv = star.X
+	}
+
+	ident, ok := recv.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+
+	if method.Body != nil && len(method.Body.List) == 1 {
+		if ret, ok := method.Body.List[0].(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+			if lit, ok := ret.Results[0].(*ast.BasicLit); ok && lit.Kind == token.INT {
+				if version, err := strconv.Atoi(lit.Value); err == nil {
+					p.versions[ident.Name] = version
+
+					return nil
+				}
+			}
+		}
+	}
+
+	return fmt.Errorf("PolyVersion of %s must return an integer literal", ident.Name)
+}
+
+// polySelector returns the name selected by expr if it is like poly.Name.
+func polySelector(expr ast.Expr, polyName string) (string, bool) {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+
+	if x, ok := sel.X.(*ast.Ident); !ok || x.Name != polyName {
+		return "", false
+	}
+
+	return sel.Sel.Name, true
+}
+
+// isWrapper reports whether the name is a generic type of the poly package wrapping a Types
+// without changing the listed types.
+func isWrapper(name string) bool {
+	switch name {
+	case "StrictPatch", "AsPointers", "AsValues", "AsDeclared":
+		return true
+	default:
+		return false
+	}
+}
+
+// variants returns the names of the Go types listed in a Types expression like
+// poly.Types2[A, *B] or poly.TypeList[A, poly.TypeList[*B, poly.TypeListLast]],
+// optionally combined with poly.ConcatN, poly.Union and poly.Without
+// and wrapped in poly.StrictPatch, poly.AsPointers, poly.AsValues and poly.AsDeclared.
+func variants(expr ast.Expr, polyName string) ([]string, error) {
+	var (
+		x       ast.Expr
+		indices []ast.Expr
+	)
+
+	switch expr := expr.(type) {
+	case *ast.SelectorExpr:
+		if name, _ := polySelector(expr, polyName); name == "TypeListLast" {
+			return nil, nil
+		}
+	case *ast.IndexExpr:
+		x, indices = expr.X, []ast.Expr{expr.Index}
+	case *ast.IndexListExpr:
+		x, indices = expr.X, expr.Indices
+	}
+
+	name, ok := polySelector(x, polyName)
+	if ok && isWrapper(name) && len(indices) == 1 {
+		return variants(indices[0], polyName)
+	}
+
+	if ok && (name == "Union" || name == "Without") && len(indices) == 2 {
+		return combine(name, indices, polyName)
+	}
+
+	if ok && strings.HasPrefix(name, "Concat") {
+		var names []string
+
+		for _, index := range indices {
+			list, err := variants(index, polyName)
+			if err != nil {
+				return nil, err
+			}
+
+			names = append(names, list...)
+		}
+
+		return names, nil
+	}
+
+	if !ok || !strings.HasPrefix(name, "Type") {
+		return nil, errors.New("unsupported Types expression")
+	}
+
+	if name == "TypeList" && len(indices) == 2 {
+		first, err := variantName(indices[0])
+		if err != nil {
+			return nil, err
+		}
+
+		rest, err := variants(indices[1], polyName)
+		if err != nil {
+			return nil, err
+		}
+
+		return append([]string{first}, rest...), nil
+	}
+
+	if !strings.HasPrefix(name, "Types") {
+		return nil, errors.New("unsupported Types expression")
+	}
+
+	names := make([]string, 0, len(indices))
+
+	for _, index := range indices {
+		name, err := variantName(index)
+		if err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// combine returns the names of the Go types listed by poly.Union or poly.Without of two Types expressions.
+func combine(name string, indices []ast.Expr, polyName string) ([]string, error) {
+	a, err := variants(indices[0], polyName)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := variants(indices[1], polyName)
+	if err != nil {
+		return nil, err
+	}
+
+	listed := make(map[string]bool, len(b))
+	for _, variant := range b {
+		listed[variant] = true
+	}
+
+	if name == "Union" {
+		for _, variant := range a {
+			delete(listed, variant)
+		}
+
+		for _, variant := range b {
+			if listed[variant] {
+				a = append(a, variant)
+			}
+		}
+
+		return a, nil
+	}
+
+	names := make([]string, 0, len(a))
+
+	for _, variant := range a {
+		if !listed[variant] {
+			names = append(names, variant)
+		}
+	}
+
+	return names, nil
+}
+
+func variantName(expr ast.Expr) (string, error) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return "", errors.New("variants must be declared in the same package")
+	}
+
+	return ident.Name, nil
+}
+
+func (g *generator) union(aliasName string, guards bool) error {
+	names := g.variants[aliasName]
+
+	for _, name := range names {
+		typeName, ok := g.pkg.typeNames[name]
+		if !ok {
+			return fmt.Errorf("%s: variant %s does not implement TypeName", aliasName, name)
+		}
+
+		if !g.emitted[name] {
+			g.emitted[name] = true
+
+			if err := g.object(name, typeName); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprintf(&g.buf, "\nexport type %s = %s;\n", aliasName, strings.Join(names, " | "))
+
+	if !guards {
+		return nil
+	}
+
+	// a guard accepts any value with a discriminator, so it is emitted once for all the aliases with the variant
+	for _, name := range names {
+		if g.guarded[name] {
+			continue
+		}
+
+		g.guarded[name] = true
+
+		fmt.Fprintf(&g.buf, "\nexport function is%s(value: { type: string }): value is %s {\n", name, name)
+		fmt.Fprintf(&g.buf, "  return value.type === %s;\n", strconv.Quote(g.pkg.typeNames[name]))
+		g.buf.WriteString("}\n")
+	}
+
+	return nil
+}
+
+// object emits the type of the struct declared as name.
+// If typeName is not empty, the struct is a variant and gets the discriminator property.
+func (g *generator) object(name, typeName string) error {
+	spec, ok := g.pkg.specs[name]
+	if !ok {
+		return fmt.Errorf("type %s is not declared", name)
+	}
+
+	st, ok := spec.Type.(*ast.StructType)
+	if !ok {
+		return fmt.Errorf("type %s is not a struct", name)
+	}
+
+	var props []string
+
+	if typeName != "" {
+		props = append(props, "type: "+strconv.Quote(typeName))
+
+		// the version must be sent, as a missing one is treated as version 1 and migrated
+		if version, ok := g.pkg.versions[name]; ok {
+			props = append(props, "version: "+strconv.Itoa(version))
+		}
+	}
+
+	fields, err := g.fields(st)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	fmt.Fprintf(&g.buf, "\nexport type %s = {\n", name)
+
+	for _, prop := range append(props, fields...) {
+		fmt.Fprintf(&g.buf, "  %s;\n", prop)
+	}
+
+	g.buf.WriteString("};\n")
+
+	return nil
+}
+
+// fields returns the TypeScript properties for the JSON encoding of st.
+func (g *generator) fields(st *ast.StructType) ([]string, error) {
+	var props []string
+
+	for _, field := range st.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			value, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			tag = reflect.StructTag(value)
+		}
+
+		jsonTag := tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+
+		jsonName, opts, _ := strings.Cut(jsonTag, ",")
+		optional := strings.Contains(","+opts+",", ",omitempty,") || strings.Contains(","+opts+",", ",omitzero,")
+
+		if len(field.Names) == 0 {
+			embedded := field.Type
+			if star, ok := embedded.(*ast.StarExpr); ok {
+				embedded = star.X
+			}
+
+			ident, ok := embedded.(*ast.Ident)
+			if !ok {
+				continue
+			}
+
+			if jsonName == "" {
+				if spec, ok := g.pkg.specs[ident.Name]; ok {
+					if inner, ok := spec.Type.(*ast.StructType); ok {
+						innerProps, err := g.fields(inner)
+						if err != nil {
+							return nil, err
+						}
+
+						props = append(props, innerProps...)
+
+						continue
+					}
+				}
+			}
+
+			if !ast.IsExported(ident.Name) {
+				continue
+			}
+
+			if jsonName == "" {
+				jsonName = ident.Name
+			}
+
+			props = append(props, property(jsonName, optional, g.tsType(field.Type)))
+
+			continue
+		}
+
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+
+			propName := jsonName
+			if propName == "" {
+				propName = name.Name
+			}
+
+			props = append(props, property(propName, optional, g.tsType(field.Type)))
+		}
+	}
+
+	return props, nil
+}
+
+func property(name string, optional bool, typ string) string {
+	if !isIdentifier(name) {
+		name = strconv.Quote(name)
+	}
+
+	if optional {
+		return name + "?: " + typ
+	}
+
+	return name + ": " + typ
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if r == '_' || r == '$' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9' {
+			continue
+		}
+
+		return false
+	}
+
+	return name != ""
+}
+
+// tsType returns the TypeScript type for the JSON encoding of the Go type expr.
+func (g *generator) tsType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return g.namedType(expr.Name)
+	case *ast.StarExpr:
+		return g.tsType(expr.X) + " | null"
+	case *ast.ArrayType:
+		if ident, ok := expr.Elt.(*ast.Ident); ok && expr.Len == nil && (ident.Name == "byte" || ident.Name == "uint8") {
+			return "string"
+		}
+
+		elem := g.tsType(expr.Elt)
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+
+		return elem + "[]"
+	case *ast.MapType:
+		return "Record<string, " + g.tsType(expr.Value) + ">"
+	case *ast.StructType:
+		fields, err := g.fields(expr)
+		if err != nil || len(fields) == 0 {
+			return "Record<string, never>"
+		}
+
+		return "{ " + strings.Join(fields, "; ") + " }"
+	case *ast.SelectorExpr:
+		if x, ok := expr.X.(*ast.Ident); ok && x.Name == "time" && expr.Sel.Name == "Time" {
+			return "string"
+		}
+
+		return "unknown"
+	default:
+		return "unknown"
+	}
+}
+
+func (g *generator) namedType(name string) string {
+	switch name {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"float32", "float64", "byte", "rune":
+		return "number"
+	}
+
+	spec, ok := g.pkg.specs[name]
+	if !ok {
+		return "unknown"
+	}
+
+	if _, ok := g.variants[name]; ok {
+		return name
+	}
+
+	if _, ok := spec.Type.(*ast.StructType); !ok {
+		return g.tsType(spec.Type)
+	}
+
+	if g.isVariant(name) {
+		// the struct is emitted as a variant, so the discriminator is dropped
+		return fmt.Sprintf("Omit<%s, \"type\">", name)
+	}
+
+	if !g.emitted[name] {
+		g.emitted[name] = true
+		g.queue = append(g.queue, name)
+	}
+
+	return name
+}
+
+func (g *generator) isVariant(name string) bool {
+	for _, names := range g.variants {
+		for _, variant := range names {
+			if variant == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const actionSource = `package action
+
+import (
+	"time"
+
+	p "github.com/ykalchevskiy/poly"
+)
+
+const deepLinkTypeName = "deep-link"
+
+type IsAction interface {
+	IsAction()
+}
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) IsAction() {}
+
+func (ActionDismiss) TypeName() string { return "dismiss" }
+
+type ActionDeepLink struct {
+	Base
+	URL     string            ` + "`json:\"url\"`" + `
+	Title   *string           ` + "`json:\"title,omitempty\"`" + `
+	Expires time.Time         ` + "`json:\"expires-at\"`" + `
+	Tags    map[string][]byte ` + "`json:\"tags\"`" + `
+	Secret  string            ` + "`json:\"-\"`" + `
+	hidden  string
+}
+
+func (*ActionDeepLink) IsAction() {}
+
+func (*ActionDeepLink) TypeName() string { return deepLinkTypeName }
+
+type ActionGroup struct {
+	Actions  []Action      ` + "`json:\"actions\"`" + `
+	Previous ActionDismiss ` + "`json:\"previous\"`" + `
+	Weight   Weight        ` + "`json:\"weight\"`" + `
+}
+
+func (ActionGroup) IsAction() {}
+
+func (ActionGroup) TypeName() string { return "group" }
+
+type Base struct {
+	ID int ` + "`json:\"id\"`" + `
+}
+
+type Weight struct {
+	Value float64
+}
+
+type Action = p.Poly[IsAction, p.TypeList[ActionDismiss, p.TypeList[*ActionDeepLink, p.Types1[ActionGroup]]]]
+`
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "action.go"), []byte(actionSource), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(dir, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by poly-ts. DO NOT EDIT.
+
+export type ActionDismiss = {
+  type: "dismiss";
+};
+
+export type ActionDeepLink = {
+  type: "deep-link";
+  id: number;
+  url: string;
+  title?: string | null;
+  "expires-at": string;
+  tags: Record<string, string>;
+};
+
+export type ActionGroup = {
+  type: "group";
+  actions: Action[];
+  previous: Omit<ActionDismiss, "type">;
+  weight: Weight;
+};
+
+export type Action = ActionDismiss | ActionDeepLink | ActionGroup;
+
+export function isActionDismiss(value: { type: string }): value is ActionDismiss {
+  return value.type === "dismiss";
+}
+
+export function isActionDeepLink(value: { type: string }): value is ActionDeepLink {
+  return value.type === "deep-link";
+}
+
+export function isActionGroup(value: { type: string }): value is ActionGroup {
+  return value.type === "group";
+}
+
+export type Weight = {
+  Value: number;
+};
+`
+
+	if string(src) != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, src)
+	}
+}
+
+func TestGenerate_strictPatch(t *testing.T) {
+	source := `package action
+
+import "github.com/ykalchevskiy/poly"
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) TypeName() string { return "dismiss" }
+
+type Action = poly.Poly[any, poly.StrictPatch[poly.AsPointers[poly.Types1[ActionDismiss]]]]
+`
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "action.go"), []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(dir, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "export type Action = ActionDismiss;"; !strings.Contains(string(src), expected) {
+		t.Fatalf("expected %q in:\n%s", expected, src)
+	}
+}
+
+func TestGenerate_buildConstraints(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, source := range map[string]string{
+		"action.go":         "package action\n\nimport \"github.com/ykalchevskiy/poly\"\n\ntype ActionDismiss struct{}\n\nfunc (ActionDismiss) TypeName() string { return \"dismiss\" }\n\ntype Action = poly.Poly[any, poly.Types1[ActionDismiss]]\n",
+		"action_ignored.go": "//go:build ignore\n\npackage action\n\nimport \"github.com/ykalchevskiy/poly\"\n\ntype Ignored = poly.Poly[any, poly.Types1[ActionDismiss]]\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src, err := generate(dir, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if strings.Contains(string(src), "Ignored") {
+		t.Fatalf("expected the ignored file to be skipped:\n%s", src)
+	}
+}
+
+func TestGenerate_versioned(t *testing.T) {
+	source := `package action
+
+import "github.com/ykalchevskiy/poly"
+
+type ActionReload struct {
+	Delay int ` + "`json:\"delay\"`" + `
+}
+
+func (ActionReload) TypeName() string { return "reload" }
+
+func (ActionReload) PolyVersion() int { return 2 }
+
+type Action = poly.Poly[any, poly.Types1[ActionReload]]
+`
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "action.go"), []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(dir, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "  type: \"reload\";\n  version: 2;\n  delay: number;\n"; !strings.Contains(string(src), expected) {
+		t.Fatalf("expected %q in:\n%s", expected, src)
+	}
+}
+
+func TestGenerate_combinators(t *testing.T) {
+	source := `package action
+
+import "github.com/ykalchevskiy/poly"
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) TypeName() string { return "dismiss" }
+
+type ActionClose struct{}
+
+func (ActionClose) TypeName() string { return "close" }
+
+type ActionOpen struct{}
+
+func (ActionOpen) TypeName() string { return "open" }
+
+type Action = poly.Poly[any, poly.Concat2[poly.Types1[ActionDismiss], poly.Types1[ActionClose]]]
+
+type BaseAction = poly.Poly[any, poly.Union[poly.Types2[ActionDismiss, ActionClose], poly.Types2[ActionClose, ActionOpen]]]
+
+type LimitedAction = poly.Poly[any, poly.Without[poly.Types3[ActionDismiss, ActionClose, ActionOpen], poly.Types1[ActionClose]]]
+`
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "action.go"), []byte(source), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(dir, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"export type Action = ActionDismiss | ActionClose;",
+		"export type BaseAction = ActionDismiss | ActionClose | ActionOpen;",
+		"export type LimitedAction = ActionDismiss | ActionOpen;",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Fatalf("expected %q in:\n%s", expected, src)
+		}
+	}
+
+	// the variants are shared by the aliases, but each guard is declared once
+	for _, guard := range []string{"isActionDismiss", "isActionClose", "isActionOpen"} {
+		if count := strings.Count(string(src), "export function "+guard+"("); count != 1 {
+			t.Fatalf("expected %s to be declared once, got %d in:\n%s", guard, count, src)
+		}
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{
+			name:   "no aliases",
+			source: "package action\n\ntype Action struct{}\n",
+			err:    "no poly.Poly aliases found",
+		},
+		{
+			name: "missing TypeName",
+			source: `package action
+
+import "github.com/ykalchevskiy/poly"
+
+type ActionDismiss struct{}
+
+type Action = poly.Poly[any, poly.Types1[ActionDismiss]]
+`,
+			err: "Action: variant ActionDismiss does not implement TypeName",
+		},
+		{
+			name: "foreign variant",
+			source: `package action
+
+import (
+	"github.com/ykalchevskiy/poly"
+	"example.com/other"
+)
+
+type Action = poly.Poly[any, poly.Types1[other.ActionDismiss]]
+`,
+			err: "Action: variants must be declared in the same package",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			if err := os.WriteFile(filepath.Join(dir, "action.go"), []byte(tt.source), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := generate(dir, false)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected %q error, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Command poly-ts generates TypeScript discriminated unions for the poly.Poly aliases of a Go package.
+//
+// Usage:
+//
+//	poly-ts [-guards] [-o output.ts] [package directory]
+//
+// For every alias like
+//
+//	type Action = poly.Poly[IsAction, poly.Types2[ActionDismiss, ActionDeepLink]]
+//
+// it emits a type per variant, built from the JSON tags of the variant struct, and the union:
+//
+//	export type ActionDismiss = {
+//	  type: "dismiss";
+//	};
+//
+//	export type ActionDeepLink = {
+//	  type: "deep-link";
+//	  url: string;
+//	};
+//
+//	export type Action = ActionDismiss | ActionDeepLink;
+//
+// With -guards, a runtime type guard like isActionDeepLink is emitted once for every variant as well.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	guards := flag.Bool("guards", false, "emit a runtime type guard for every variant")
+	output := flag.String("o", "", "output file (default stdout)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: poly-ts [-guards] [-o output.ts] [package directory]\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, *output, *guards); err != nil {
+		fmt.Fprintf(os.Stderr, "poly-ts: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output string, guards bool) error {
+	src, err := generate(dir, guards)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+
+		return err
+	}
+
+	return os.WriteFile(output, src, 0o644) //nolint:gosec
+}
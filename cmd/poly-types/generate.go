@@ -0,0 +1,446 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const polyPath = "github.com/ykalchevskiy/poly"
+
+// maxTypes is the maximum number of types of poly.TypesN and lists of poly.ConcatN.
+const maxTypes = 9
+
+// pkg holds the declarations of a parsed package needed to find the types implementing an interface.
+type pkg struct {
+	name       string
+	types      []string // names of the non-interface types in the order of declaration
+	interfaces map[string]*ast.InterfaceType
+	typeNames  map[token.Pos]*ast.FuncDecl // position of the name -> TypeName method
+	consts     map[string]string           // string constants
+	polyNames  map[string]string           // interface name -> local name of the poly package in its file
+	checked    *types.Package              // the type-checked package to resolve the method sets
+}
+
+// variant is a type implementing the interface.
+type variant struct {
+	expr     string // the type expression, e.g. A or *B
+	typeName string
+}
+
+func generate(dir, iface, aliasName string, constructor bool) ([]byte, error) {
+	p, err := parsePackage(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := p.interfaces[iface]; !ok {
+		return nil, fmt.Errorf("interface %s not found in %s", iface, dir)
+	}
+
+	required, err := p.methodSet(iface, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", iface, err)
+	}
+
+	required["TypeName"] = true
+
+	variants, err := p.implementations(required)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("no types implementing %s with TypeName found in %s", iface, dir)
+	}
+
+	names := make([]string, len(variants))
+	for i, v := range variants {
+		names[i] = v.expr
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "// Code generated by poly-types. DO NOT EDIT.\n\npackage %s\n\nimport %q\n\n", p.name, polyPath)
+
+	if !constructor {
+		fmt.Fprintf(&b, "type %s = poly.Poly[%s, %s]\n", aliasName, iface, typesExpr(names))
+
+		return format.Source([]byte(b.String()))
+	}
+
+	typesName := aliasName + "Types"
+
+	fmt.Fprintf(&b, "type %s = poly.Poly[%s, %s]\n\n", aliasName, iface, typesName)
+	fmt.Fprintf(&b, "// %s lists the types of %s and implements poly.TypesConstructor.\n", typesName, aliasName)
+	fmt.Fprintf(&b, "type %s struct {\n%s\n}\n\n", typesName, typesExpr(names))
+
+	fmt.Fprintf(&b, "func (%s) NewValue(typeName string, current any) (any, poly.ValueGetter, bool) {\nswitch typeName {\n", typesName)
+
+	for _, v := range variants {
+		fmt.Fprintf(&b, "case %q:\nreturn poly.NewValueOf[%s](current)\n", v.typeName, v.expr)
+	}
+
+	b.WriteString("}\n\nreturn nil, nil, false\n}\n\n")
+
+	fmt.Fprintf(&b, "func (%s) TypeNameOf(value any) (string, bool, bool) {\nswitch value := value.(type) {\n", typesName)
+
+	for _, v := range variants {
+		nilPointer := "false"
+		if strings.HasPrefix(v.expr, "*") {
+			nilPointer = "value == nil"
+		}
+
+		fmt.Fprintf(&b, "case %s:\nreturn %q, %s, true\n", v.expr, v.typeName, nilPointer)
+	}
+
+	b.WriteString("}\n\nreturn \"\", false, false\n}\n")
+
+	return format.Source([]byte(b.String()))
+}
+
+// parsePackage parses the files of the package in dir selected by the build constraints, excluding tests,
+// and type-checks them to resolve the method sets of the types.
+func parsePackage(dir string) (*pkg, error) {
+	fset := token.NewFileSet()
+
+	buildPkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := append(append([]string(nil), buildPkg.GoFiles...), buildPkg.CgoFiles...)
+	sort.Strings(paths)
+
+	p := &pkg{
+		name:       buildPkg.Name,
+		interfaces: make(map[string]*ast.InterfaceType),
+		typeNames:  make(map[token.Pos]*ast.FuncDecl),
+		consts:     make(map[string]string),
+		polyNames:  make(map[string]string),
+	}
+
+	files := make([]*ast.File, 0, len(paths))
+
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, path), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+
+		for _, decl := range file.Decls {
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				p.addMethod(decl)
+			case *ast.GenDecl:
+				p.addGenDecl(decl, file)
+			}
+		}
+	}
+
+	// only the method sets are needed, so the errors, e.g. of the imports which cannot be resolved, are ignored,
+	// and implementations fails on the embedded fields of the types which are not resolved instead
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil), Error: func(error) {}}
+	p.checked, _ = conf.Check(p.name, fset, files, nil)
+
+	return p, nil
+}
+
+func (p *pkg) addGenDecl(decl *ast.GenDecl, file *ast.File) {
+	for _, spec := range decl.Specs {
+		switch spec := spec.(type) {
+		case *ast.TypeSpec:
+			if spec.Assign != token.NoPos || spec.TypeParams != nil {
+				continue
+			}
+
+			if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+				p.interfaces[spec.Name.Name] = iface
+				p.polyNames[spec.Name.Name] = importName(file)
+
+				continue
+			}
+
+			p.types = append(p.types, spec.Name.Name)
+		case *ast.ValueSpec:
+			if decl.Tok != token.CONST {
+				continue
+			}
+
+			for i, name := range spec.Names {
+				if i >= len(spec.Values) {
+					break
+				}
+
+				if lit, ok := spec.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					if value, err := strconv.Unquote(lit.Value); err == nil {
+						p.consts[name.Name] = value
+					}
+				}
+			}
+		}
+	}
+}
+
+func (p *pkg) addMethod(decl *ast.FuncDecl) {
+	if decl.Recv != nil && decl.Name.Name == "TypeName" {
+		p.typeNames[decl.Name.Pos()] = decl
+	}
+}
+
+// importName returns the local name of the poly package in file, or "" if it is not imported.
+func importName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if path, _ := strconv.Unquote(spec.Path.Value); path != polyPath {
+			continue
+		}
+
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+
+		return "poly"
+	}
+
+	return ""
+}
+
+// methodSet returns the names of the methods of the interface including the embedded ones.
+func (p *pkg) methodSet(name string, visited map[string]bool) (map[string]bool, error) {
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
+
+	if visited[name] {
+		return nil, fmt.Errorf("interface %s embeds itself", name)
+	}
+
+	visited[name] = true
+
+	iface, ok := p.interfaces[name]
+	if !ok {
+		return nil, fmt.Errorf("interface %s not found", name)
+	}
+
+	methods := make(map[string]bool)
+
+	for _, field := range iface.Methods.List {
+		for _, method := range field.Names {
+			methods[method.Name] = true
+		}
+
+		if len(field.Names) > 0 {
+			continue
+		}
+
+		switch embedded := field.Type.(type) {
+		case *ast.Ident:
+			embeddedMethods, err := p.methodSet(embedded.Name, visited)
+			if err != nil {
+				return nil, err
+			}
+
+			for method := range embeddedMethods {
+				methods[method] = true
+			}
+		case *ast.SelectorExpr:
+			if x, ok := embedded.X.(*ast.Ident); !ok || x.Name != p.polyNames[name] || embedded.Sel.Name != "TypeName" {
+				return nil, errors.New("only local interfaces and poly.TypeName can be embedded")
+			}
+
+			methods["TypeName"] = true
+		default:
+			return nil, errors.New("only local interfaces and poly.TypeName can be embedded")
+		}
+	}
+
+	return methods, nil
+}
+
+// implementations returns the types having all the required methods, including the promoted ones,
+// sorted by name, checking that their TypeNames are unique.
+func (p *pkg) implementations(required map[string]bool) ([]variant, error) {
+	var variants []variant
+
+	typeNames := make(map[string]string) // TypeName -> Go type name
+
+	for _, name := range p.types {
+		obj, ok := p.checked.Scope().Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+
+		pointer := false
+
+		if !p.hasMethods(obj.Type(), required) {
+			if !p.hasMethods(types.NewPointer(obj.Type()), required) {
+				if err := unresolvedEmbedded(obj); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			pointer = true
+		}
+
+		typeName, err := p.typeName(name, obj)
+		if err != nil {
+			return nil, err
+		}
+
+		if other, ok := typeNames[typeName]; ok {
+			return nil, fmt.Errorf("duplicate TypeName %q of %s and %s", typeName, other, name)
+		}
+
+		typeNames[typeName] = name
+
+		if pointer {
+			name = "*" + name
+		}
+
+		variants = append(variants, variant{expr: name, typeName: typeName})
+	}
+
+	sort.Slice(variants, func(i, j int) bool {
+		return strings.TrimPrefix(variants[i].expr, "*") < strings.TrimPrefix(variants[j].expr, "*")
+	})
+
+	return variants, nil
+}
+
+// hasMethods reports whether the method set of typ has all the required methods.
+func (p *pkg) hasMethods(typ types.Type, required map[string]bool) bool {
+	methodSet := types.NewMethodSet(typ)
+
+	for method := range required {
+		if methodSet.Lookup(p.checked, method) == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unresolvedEmbedded returns an error if the struct type embeds a field whose type is not resolved,
+// as it could promote the methods of the interface, so the type would be missing silently.
+func unresolvedEmbedded(obj *types.TypeName) error {
+	st, ok := obj.Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if !field.Embedded() {
+			continue
+		}
+
+		typ := field.Type()
+		if ptr, ok := typ.(*types.Pointer); ok {
+			typ = ptr.Elem()
+		}
+
+		if typ == types.Typ[types.Invalid] || typ.Underlying() == types.Typ[types.Invalid] {
+			return fmt.Errorf("cannot resolve the methods of the embedded field %s of %s", field.Name(), obj.Name())
+		}
+	}
+
+	return nil
+}
+
+// typeName returns the TypeName of the type, which must be returned as a string literal or constant.
+// The method can be declared on the type or promoted from an embedded type of the package.
+func (p *pkg) typeName(name string, obj *types.TypeName) (string, error) {
+	selection := types.NewMethodSet(types.NewPointer(obj.Type())).Lookup(nil, "TypeName")
+
+	method, ok := p.typeNames[selection.Obj().Pos()]
+	if !ok {
+		return "", fmt.Errorf("TypeName of %s must be declared in the package", name)
+	}
+
+	if method.Body == nil || len(method.Body.List) != 1 {
+		return "", fmt.Errorf("TypeName of %s must be a single return statement", name)
+	}
+
+	ret, ok := method.Body.List[0].(*ast.ReturnStmt)
+	if !ok || len(ret.Results) != 1 {
+		return "", fmt.Errorf("TypeName of %s must be a single return statement", name)
+	}
+
+	switch result := ret.Results[0].(type) {
+	case *ast.BasicLit:
+		value, err := strconv.Unquote(result.Value)
+		if err != nil || result.Kind != token.STRING {
+			return "", fmt.Errorf("TypeName of %s must return a string", name)
+		}
+
+		return value, nil
+	case *ast.Ident:
+		value, ok := p.consts[result.Name]
+		if !ok {
+			return "", fmt.Errorf("TypeName of %s must return a string constant", name)
+		}
+
+		return value, nil
+	default:
+		return "", fmt.Errorf("TypeName of %s must return a string literal or constant", name)
+	}
+}
+
+// typesExpr returns the expression listing the types with poly.TypesN, combined with poly.ConcatN if needed.
+func typesExpr(names []string) string {
+	if len(names) <= maxTypes {
+		return fmt.Sprintf("poly.Types%d[%s]", len(names), strings.Join(names, ", "))
+	}
+
+	var lists []string
+
+	for len(names) > 0 {
+		n := maxTypes
+		if len(names) < n {
+			n = len(names)
+		}
+
+		lists = append(lists, typesExpr(names[:n]))
+		names = names[n:]
+	}
+
+	return concatExpr(lists)
+}
+
+func concatExpr(lists []string) string {
+	for len(lists) > maxTypes {
+		var groups []string
+
+		for len(lists) > 0 {
+			n := maxTypes
+			if len(lists) < n {
+				n = len(lists)
+			}
+
+			groups = append(groups, concatExpr(lists[:n]))
+			lists = lists[n:]
+		}
+
+		lists = groups
+	}
+
+	if len(lists) == 1 {
+		return lists[0]
+	}
+
+	return fmt.Sprintf("poly.Concat%d[\n%s,\n]", len(lists), strings.Join(lists, ",\n"))
+}
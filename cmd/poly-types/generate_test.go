@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const actionSource = `package action
+
+import p "github.com/ykalchevskiy/poly"
+
+const deepLinkTypeName = "deep-link"
+
+type IsAction interface {
+	Marker
+	p.TypeName
+}
+
+type Marker interface {
+	IsAction()
+}
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) IsAction() {}
+
+func (ActionDismiss) TypeName() string { return "dismiss" }
+
+type ActionDeepLink struct {
+	URL string
+}
+
+func (*ActionDeepLink) IsAction() {}
+
+func (ActionDeepLink) TypeName() string { return deepLinkTypeName }
+
+type ActionGroup struct{}
+
+func (ActionGroup) IsAction() {}
+
+func (ActionGroup) TypeName() string { return "group" }
+
+type NotAction struct{}
+
+func (NotAction) TypeName() string { return "not-action" }
+
+type NoTypeName struct{}
+
+func (NoTypeName) IsAction() {}
+
+type Generic[T any] struct{}
+
+func (Generic[T]) IsAction() {}
+
+func (Generic[T]) TypeName() string { return "generic" }
+`
+
+func writeSource(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestGenerate(t *testing.T) {
+	dir := writeSource(t, map[string]string{
+		"action.go":      actionSource,
+		"action_test.go": "package action\n\ntype ActionTest struct{}\n\nfunc (ActionTest) IsAction() {}\n\nfunc (ActionTest) TypeName() string { return \"test\" }\n",
+	})
+
+	src, err := generate(dir, "IsAction", "Action", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by poly-types. DO NOT EDIT.
+
+package action
+
+import "github.com/ykalchevskiy/poly"
+
+type Action = poly.Poly[IsAction, poly.Types3[*ActionDeepLink, ActionDismiss, ActionGroup]]
+`
+	if string(src) != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, src)
+	}
+}
+
+func TestGenerate_embedded(t *testing.T) {
+	dir := writeSource(t, map[string]string{
+		"action.go": `package action
+
+type IsAction interface{ IsAction() }
+
+type ActionBase struct{}
+
+func (ActionBase) IsAction() {}
+
+type ActionPointerBase struct{}
+
+func (*ActionPointerBase) IsAction() {}
+
+type ActionDismiss struct{ ActionBase }
+
+func (ActionDismiss) TypeName() string { return "dismiss" }
+
+type ActionOpen struct{ ActionPointerBase }
+
+func (ActionOpen) TypeName() string { return "open" }
+`,
+		"action_ignored.go": "//go:build ignore\n\npackage action\n\ntype ActionIgnored struct{ ActionBase }\n\nfunc (ActionIgnored) TypeName() string { return \"ignored\" }\n",
+	})
+
+	src, err := generate(dir, "IsAction", "Action", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// ActionBase and ActionPointerBase do not have TypeName, and ActionIgnored is excluded by its build constraint
+	if expected := "type Action = poly.Poly[IsAction, poly.Types2[ActionDismiss, *ActionOpen]]"; !strings.Contains(string(src), expected) {
+		t.Fatalf("expected %s in:\n%s", expected, src)
+	}
+}
+
+func TestGenerate_concat(t *testing.T) {
+	var b strings.Builder
+
+	b.WriteString("package action\n\ntype IsAction interface{ IsAction() }\n")
+
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&b, "\ntype A%02d struct{}\n\nfunc (A%02d) IsAction() {}\n\nfunc (A%02d) TypeName() string { return \"a%d\" }\n", i, i, i, i)
+	}
+
+	dir := writeSource(t, map[string]string{"action.go": b.String()})
+
+	src, err := generate(dir, "IsAction", "Action", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by poly-types. DO NOT EDIT.
+
+package action
+
+import "github.com/ykalchevskiy/poly"
+
+type Action = poly.Poly[IsAction, poly.Concat3[
+	poly.Types9[A01, A02, A03, A04, A05, A06, A07, A08, A09],
+	poly.Types9[A10, A11, A12, A13, A14, A15, A16, A17, A18],
+	poly.Types2[A19, A20],
+]]
+`
+	if string(src) != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, src)
+	}
+}
+
+func TestGenerate_errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		err    string
+	}{
+		{
+			name:   "duplicate",
+			source: "package action\n\ntype IsAction interface{ IsAction() }\n\ntype A struct{}\n\nfunc (A) IsAction() {}\n\nfunc (A) TypeName() string { return \"a\" }\n\ntype B struct{}\n\nfunc (*B) IsAction() {}\n\nfunc (*B) TypeName() string { return \"a\" }\n",
+			err:    `duplicate TypeName "a" of A and B`,
+		},
+		{
+			name:   "not constant",
+			source: "package action\n\ntype IsAction interface{ IsAction() }\n\ntype A struct{ name string }\n\nfunc (A) IsAction() {}\n\nfunc (a A) TypeName() string { return a.name }\n",
+			err:    "TypeName of A must return a string literal or constant",
+		},
+		{
+			name:   "missing interface",
+			source: "package action\n",
+			err:    "interface IsAction not found",
+		},
+		{
+			name:   "no types",
+			source: "package action\n\ntype IsAction interface{ IsAction() }\n",
+			err:    "no types implementing IsAction",
+		},
+		{
+			name:   "unresolved embedded field",
+			source: "package action\n\nimport \"example.com/missing\"\n\ntype IsAction interface{ IsAction() }\n\ntype A struct{ missing.Base }\n\nfunc (A) TypeName() string { return \"a\" }\n",
+			err:    "cannot resolve the methods of the embedded field Base of A",
+		},
+		{
+			name:   "foreign embedded interface",
+			source: "package action\n\nimport \"fmt\"\n\ntype IsAction interface{ fmt.Stringer }\n",
+			err:    "only local interfaces and poly.TypeName can be embedded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSource(t, map[string]string{"action.go": tt.source})
+
+			_, err := generate(dir, "IsAction", "Action", false)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := writeSource(t, map[string]string{"action.go": actionSource})
+
+	if err := run(dir, "IsAction", "Action", false, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	src, err := os.ReadFile(filepath.Join(dir, "action_poly.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(src), "type Action = poly.Poly[IsAction, poly.Types3[*ActionDeepLink, ActionDismiss, ActionGroup]]") {
+		t.Fatalf("unexpected output:\n%s", src)
+	}
+
+	// the generated file is scanned on the next run
+	if err := run(dir, "IsAction", "Action", false, ""); err != nil {
+		t.Fatalf("unexpected error on regeneration: %v", err)
+	}
+}
+
+func TestGenerate_constructor(t *testing.T) {
+	dir := writeSource(t, map[string]string{"action.go": actionSource})
+
+	src, err := generate(dir, "IsAction", "Action", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `// Code generated by poly-types. DO NOT EDIT.
+
+package action
+
+import "github.com/ykalchevskiy/poly"
+
+type Action = poly.Poly[IsAction, ActionTypes]
+
+// ActionTypes lists the types of Action and implements poly.TypesConstructor.
+type ActionTypes struct {
+	poly.Types3[*ActionDeepLink, ActionDismiss, ActionGroup]
+}
+
+func (ActionTypes) NewValue(typeName string, current any) (any, poly.ValueGetter, bool) {
+	switch typeName {
+	case "deep-link":
+		return poly.NewValueOf[*ActionDeepLink](current)
+	case "dismiss":
+		return poly.NewValueOf[ActionDismiss](current)
+	case "group":
+		return poly.NewValueOf[ActionGroup](current)
+	}
+
+	return nil, nil, false
+}
+
+func (ActionTypes) TypeNameOf(value any) (string, bool, bool) {
+	switch value := value.(type) {
+	case *ActionDeepLink:
+		return "deep-link", value == nil, true
+	case ActionDismiss:
+		return "dismiss", false, true
+	case ActionGroup:
+		return "group", false, true
+	}
+
+	return "", false, false
+}
+`
+	if string(src) != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, src)
+	}
+}
@@ -0,0 +1,78 @@
+// Command poly-types generates a poly.Poly alias listing all the types of a Go package
+// which implement an interface and have a TypeName method.
+//
+// Usage:
+//
+//	poly-types -interface IsAction -alias Action [-constructor] [-o output.go] [package directory]
+//
+// It is intended to be used with go:generate next to the interface:
+//
+//	//go:generate go run github.com/ykalchevskiy/poly/cmd/poly-types -interface IsAction -alias Action
+//
+// and writes (to action_poly.go by default):
+//
+//	type Action = poly.Poly[IsAction, poly.Types2[ActionDeepLink, ActionDismiss]]
+//
+// The types are listed by name, as pointers if they implement the interface only as pointers,
+// and more than nine of them are combined with poly.ConcatN. The methods promoted from embedded fields count,
+// and only the files selected by the build constraints are read.
+// It fails if several types have the same TypeName, which must be a string literal or constant.
+//
+// With -constructor, the types are listed by a generated ActionTypes struct implementing poly.TypesConstructor,
+// so Poly creates and identifies the values without reflection.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	iface := flag.String("interface", "", "name of the interface implemented by the types (required)")
+	aliasName := flag.String("alias", "", "name of the generated poly.Poly alias (required)")
+	constructor := flag.Bool("constructor", false, "generate a types list implementing poly.TypesConstructor")
+	output := flag.String("o", "", "output file (default <alias>_poly.go in the package directory, - for stdout)")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: poly-types -interface name -alias name [-constructor] [-o output.go] [package directory]\n")
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, *iface, *aliasName, *constructor, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "poly-types: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, iface, aliasName string, constructor bool, output string) error {
+	if iface == "" || aliasName == "" {
+		return errors.New("-interface and -alias are required")
+	}
+
+	src, err := generate(dir, iface, aliasName, constructor)
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case "-":
+		_, err = os.Stdout.Write(src)
+
+		return err
+	case "":
+		output = filepath.Join(dir, strings.ToLower(aliasName)+"_poly.go")
+	}
+
+	return os.WriteFile(output, src, 0o644) //nolint:gosec
+}
@@ -0,0 +1,119 @@
+package poly
+
+import "fmt"
+
+// TypesConstructor can be implemented by a Types list, e.g. one generated by poly-types with -constructor,
+// to create the values of the listed types and resolve their TypeNames without reflection.
+// Poly uses it when T implements it, falling back to reflection for the values it does not recognize:
+//
+//	type ActionTypes struct {
+//		poly.Types2[ActionDismiss, *ActionDeepLink]
+//	}
+//
+//	func (ActionTypes) NewValue(typeName string, current any) (any, poly.ValueGetter, bool) {
+//		switch typeName {
+//		case "dismiss":
+//			return poly.NewValueOf[ActionDismiss](current)
+//		case "deep-link":
+//			return poly.NewValueOf[*ActionDeepLink](current)
+//		}
+//
+//		return nil, nil, false
+//	}
+//
+//	func (ActionTypes) TypeNameOf(value any) (string, bool, bool) {
+//		switch value := value.(type) {
+//		case ActionDismiss:
+//			return "dismiss", false, true
+//		case *ActionDeepLink:
+//			return "deep-link", value == nil, true
+//		}
+//
+//		return "", false, false
+//	}
+type TypesConstructor interface {
+	Types
+
+	// NewValue returns a pointer to a new value of the listed type with the TypeName, which is a copy
+	// of current if current is of that type, and the ValueGetter of the type.
+	// It returns false if no listed type has the TypeName.
+	NewValue(typeName string, current any) (ptr any, getter ValueGetter, ok bool)
+
+	// TypeNameOf returns the TypeName of the value of a listed type and whether the value is a nil pointer.
+	// It returns false if the value is not of a listed type.
+	TypeNameOf(value any) (typeName string, nilPointer bool, ok bool)
+}
+
+// ValueGetter returns the value pointed to by a pointer created by TypesConstructor.NewValue.
+// It does not depend on the pointer, so the same ValueGetter is returned for all values of a type.
+type ValueGetter interface {
+	Value(ptr any) any
+}
+
+// NewValueOf implements TypesConstructor.NewValue for the listed type V.
+func NewValueOf[V any](current any) (any, ValueGetter, bool) {
+	ptr := new(V)
+
+	if value, ok := current.(V); ok {
+		*ptr = value
+	}
+
+	return ptr, valueGetter[V]{}, true
+}
+
+// valueGetter is the ValueGetter of NewValueOf, it has no fields, so it is converted to an interface without allocating.
+type valueGetter[V any] struct{}
+
+func (valueGetter[V]) Value(ptr any) any {
+	return *ptr.(*V) //nolint:forcetypeassert
+}
+
+// constructorOf returns T, or the Types wrapped by it, as a TypesConstructor if it implements it.
+func constructorOf[T Types]() (TypesConstructor, bool) {
+	return typesOption[TypesConstructor, T]()
+}
+
+// decodeConstructed is decodeValue using the TypesConstructor instead of reflection.
+// currentTypeName is the TypeName of the current value, if any.
+func (p *Poly[I, T]) decodeConstructed(
+	constructor TypesConstructor, currentTypeName string, nilPointer bool, typeName string, decode func(ptr any) error,
+) (Type, error) {
+	if typeName == "" {
+		typeName = currentTypeName
+	}
+
+	typ, err := lookupDiscriminator[T](typeName)
+	if err != nil {
+		return Type{}, err
+	}
+
+	if !nilPointer {
+		if err := checkTypeChange[T](currentTypeName, typ.Name); err != nil {
+			return Type{}, err
+		}
+	}
+
+	// the current value of the same type is copied to be patched, a pointer is patched in place
+	var current any
+	if currentTypeName == typ.Name {
+		current = p.Value
+	}
+
+	ptr, getter, ok := constructor.NewValue(typ.Name, current)
+	if !ok {
+		return Type{}, fmt.Errorf("poly: cannot create %s to unmarshal", typ.Name)
+	}
+
+	if err := decode(ptr); err != nil {
+		return Type{}, fmt.Errorf("poly: cannot unmarshal '%s': %w", typ.ReflectType, err)
+	}
+
+	value, ok := getter.Value(ptr).(I)
+	if !ok {
+		return Type{}, fmt.Errorf("poly: cannot use '%v' as I", ptr)
+	}
+
+	p.Value = value
+
+	return typ, nil
+}
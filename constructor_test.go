@@ -0,0 +1,228 @@
+package poly_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+type ItemValueTypes struct {
+	poly.Types2[ItemValue1, ItemValue2]
+}
+
+func (ItemValueTypes) NewValue(typeName string, current any) (any, poly.ValueGetter, bool) {
+	switch typeName {
+	case "item-value-1":
+		return poly.NewValueOf[ItemValue1](current)
+	case "item-value-2":
+		return poly.NewValueOf[ItemValue2](current)
+	}
+
+	return nil, nil, false
+}
+
+func (ItemValueTypes) TypeNameOf(value any) (string, bool, bool) {
+	switch value.(type) {
+	case ItemValue1:
+		return "item-value-1", false, true
+	case ItemValue2:
+		return "item-value-2", false, true
+	}
+
+	return "", false, false
+}
+
+type ItemPointerTypes struct {
+	poly.Types2[*ItemPointer1, *ItemPointer2]
+}
+
+func (ItemPointerTypes) NewValue(typeName string, current any) (any, poly.ValueGetter, bool) {
+	switch typeName {
+	case "item-pointer-1":
+		return poly.NewValueOf[*ItemPointer1](current)
+	case "item-pointer-2":
+		return poly.NewValueOf[*ItemPointer2](current)
+	}
+
+	return nil, nil, false
+}
+
+func (ItemPointerTypes) TypeNameOf(value any) (string, bool, bool) {
+	switch value := value.(type) {
+	case *ItemPointer1:
+		return "item-pointer-1", value == nil, true
+	case *ItemPointer2:
+		return "item-pointer-2", value == nil, true
+	}
+
+	return "", false, false
+}
+
+type ItemValueConstructed = poly.Poly[IsItemValue, ItemValueTypes]
+
+type ItemPointerConstructed = poly.Poly[IsItemPointer, ItemPointerTypes]
+
+func TestTypesConstructor(t *testing.T) {
+	t.Run("value", func(t *testing.T) {
+		var item ItemValueConstructed
+		bIn := []byte(`{"type":"item-value-2","key":"k","key2":"k2"}`)
+
+		if err := json.Unmarshal(bIn, &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if _, ok := item.Value.(ItemValue2); !ok {
+			t.Fatalf("expected ItemValue2, got %T", item.Value)
+		}
+
+		if err := json.Unmarshal([]byte(`{"key":"k3"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		bOut, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if expected := `{"type":"item-value-2","key":"k3","key2":"k2"}`; string(bOut) != expected {
+			t.Fatalf("expected %s, got %s", expected, bOut)
+		}
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-1"}`), &item); err != nil {
+			t.Fatalf("unmarshaling new type error: %v", err)
+		}
+
+		if _, ok := item.Value.(ItemValue1); !ok {
+			t.Fatalf("expected ItemValue1, got %T", item.Value)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		value := &ItemPointer2{Key: "k", Key2: "k2"}
+		item := ItemPointerConstructed{Value: value}
+
+		if err := json.Unmarshal([]byte(`{"key":"k3"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if item.Value != value {
+			t.Fatalf("expected the pointer to be patched in place, got %#v", item.Value)
+		}
+
+		if expected := (ItemPointer2{Key: "k3", Key2: "k2"}); *value != expected {
+			t.Fatalf("expected %#v, got %#v", expected, *value)
+		}
+	})
+
+	t.Run("nil pointer", func(t *testing.T) {
+		item := ItemPointerConstructed{Value: (*ItemPointer2)(nil)}
+
+		if _, ok, err := item.ResolveType(); ok || err != nil {
+			t.Fatalf("expected no type, got %v, %v", ok, err)
+		}
+
+		if err := json.Unmarshal([]byte(`{"key":"k"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if got, ok := item.Value.(*ItemPointer2); !ok || got == nil || got.Key != "k" {
+			t.Fatalf("expected ItemPointer2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		item := ItemPointerConstructed{Value: &ItemPointerUnknown{}}
+
+		_, err := json.Marshal(item)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-pointer-unknown of *poly_test.ItemPointerUnknown to marshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+
+		err = json.Unmarshal([]byte(`{"type":"item-pointer-unknown"}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-pointer-unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+
+	t.Run("same as reflection", func(t *testing.T) {
+		for _, data := range []string{
+			`{"type":"item-pointer-1"}`,
+			`{"type":"item-pointer-2","key":"k"}`,
+		} {
+			var reflected ItemPointer
+			var constructed ItemPointerConstructed
+
+			if err := json.Unmarshal([]byte(data), &reflected); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+
+			if err := json.Unmarshal([]byte(data), &constructed); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+
+			bReflected, err := json.Marshal(reflected)
+			if err != nil {
+				t.Fatalf("marshaling error: %v", err)
+			}
+
+			bConstructed, err := json.Marshal(constructed)
+			if err != nil {
+				t.Fatalf("marshaling error: %v", err)
+			}
+
+			if !bytes.Equal(bReflected, bConstructed) {
+				t.Fatalf("expected %s, got %s", bReflected, bConstructed)
+			}
+		}
+	})
+}
+
+func BenchmarkPoly_MarshalJSON(b *testing.B) {
+	b.Run("reflection", func(b *testing.B) {
+		benchmarkMarshalJSON(b, ItemPointer{Value: &ItemPointer2{Key: "k"}})
+	})
+
+	b.Run("constructor", func(b *testing.B) {
+		benchmarkMarshalJSON(b, ItemPointerConstructed{Value: &ItemPointer2{Key: "k"}})
+	})
+}
+
+func benchmarkMarshalJSON(b *testing.B, item any) {
+	b.Helper()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(item); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPoly_UnmarshalJSON(b *testing.B) {
+	data := []byte(`{"type":"item-pointer-2","key":"k"}`)
+
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			var item ItemPointer
+			if err := json.Unmarshal(data, &item); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("constructor", func(b *testing.B) {
+		b.ReportAllocs()
+
+		for i := 0; i < b.N; i++ {
+			var item ItemPointerConstructed
+			if err := json.Unmarshal(data, &item); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package poly
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder reads a stream of Poly values from an io.Reader record by record.
+// The stream is either a sequence of JSON values, e.g. JSON Lines, or a single top-level JSON array,
+// which is detected by its first character.
+//
+// The records can be read with Decode, or iterated with Next, Value and Err:
+//
+//	dec := poly.NewDecoder[IsAction, poly.Types2[ActionDismiss, ActionDeepLink]](r)
+//	for dec.Next() {
+//		action := dec.Value()
+//		// ...
+//	}
+//	if err := dec.Err(); err != nil {
+//		// ...
+//	}
+type Decoder[I any, T Types] struct {
+	lines   *lineReader
+	r       *bufio.Reader
+	dec     *json.Decoder
+	skipped int64 // the number of bytes read before dec
+	array   bool
+	index   int
+	value   I
+	err     error
+}
+
+// DecodeError is returned by Decoder when a record cannot be decoded.
+type DecodeError struct {
+	Index  int   // the index of the record in the stream, starting from 0
+	Line   int   // the line of the beginning of the record, starting from 1
+	Offset int64 // the offset of the beginning of the record in bytes
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("poly: cannot decode record %d at line %d, offset %d: %v", e.Index, e.Line, e.Offset, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// NewDecoder creates a new Decoder reading from r.
+func NewDecoder[I any, T Types](r io.Reader) *Decoder[I, T] {
+	lines := &lineReader{r: r}
+
+	return &Decoder[I, T]{
+		lines: lines,
+		r:     bufio.NewReader(lines),
+	}
+}
+
+// Decode decodes the next record. It returns io.EOF at the end of the stream.
+// If the record is valid JSON but cannot be decoded into the Poly, e.g. its TypeName is unknown,
+// the *DecodeError is returned and the following records can still be decoded.
+// Other errors, e.g. syntax errors, are returned by all the following calls.
+func (d *Decoder[I, T]) Decode() (I, error) {
+	var zero I
+
+	if d.err != nil {
+		return zero, d.err
+	}
+
+	if d.dec == nil {
+		if err := d.start(); err != nil {
+			return zero, d.fail(err)
+		}
+	}
+
+	if d.array && !d.dec.More() {
+		if _, err := d.dec.Token(); err != nil {
+			return zero, d.fail(err)
+		}
+
+		if _, err := d.dec.Token(); !errors.Is(err, io.EOF) {
+			return zero, d.fail(errors.New("unexpected data after top-level array"))
+		}
+
+		d.err = io.EOF
+
+		return zero, io.EOF
+	}
+
+	offset := d.recordOffset()
+	line := d.lines.line(offset) // also releases the newlines before the record
+	index := d.index
+	d.index++
+
+	var p Poly[I, T]
+
+	if err := d.dec.Decode(&p); err != nil {
+		if errors.Is(err, io.EOF) {
+			if !d.array {
+				d.err = io.EOF
+
+				return zero, io.EOF
+			}
+
+			err = io.ErrUnexpectedEOF
+		}
+
+		decodeErr := &DecodeError{Index: index, Line: line, Offset: offset, Err: err}
+
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+			d.err = decodeErr
+		}
+
+		return zero, decodeErr
+	}
+
+	return p.Value, nil
+}
+
+// Next decodes the next record, which is then available via Value.
+// It returns false at the end of the stream or on the first error, which is available via Err.
+func (d *Decoder[I, T]) Next() bool {
+	value, err := d.Decode()
+	if err != nil {
+		if d.err == nil {
+			d.err = err
+		}
+
+		return false
+	}
+
+	d.value = value
+
+	return true
+}
+
+// Value returns the record decoded by the last call to Next.
+func (d *Decoder[I, T]) Value() I {
+	return d.value
+}
+
+// Err returns the error that stopped Next, or nil if the stream ended successfully.
+func (d *Decoder[I, T]) Err() error {
+	if errors.Is(d.err, io.EOF) {
+		return nil
+	}
+
+	return d.err
+}
+
+// start detects the format of the stream and creates the JSON decoder.
+func (d *Decoder[I, T]) start() error {
+	for {
+		c, err := d.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !isSpace(c) {
+			d.array = c == '['
+
+			if err := d.r.UnreadByte(); err != nil {
+				return err
+			}
+
+			break
+		}
+
+		d.skipped++
+	}
+
+	d.dec = json.NewDecoder(d.r)
+
+	if d.array {
+		if _, err := d.dec.Token(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordOffset returns the offset of the beginning of the next record skipping the buffered
+// whitespace and separators after the previous one.
+func (d *Decoder[I, T]) recordOffset() int64 {
+	offset := d.skipped + d.dec.InputOffset()
+
+	buffered, ok := d.dec.Buffered().(io.ByteReader)
+	if !ok {
+		return offset
+	}
+
+	for {
+		c, err := buffered.ReadByte()
+		if err != nil || !isSpace(c) && c != ',' {
+			return offset
+		}
+
+		offset++
+	}
+}
+
+func (d *Decoder[I, T]) fail(err error) error {
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+
+	offset := d.skipped
+	if d.dec != nil {
+		offset += d.dec.InputOffset()
+	}
+
+	d.err = &DecodeError{Index: d.index, Line: d.lines.line(offset), Offset: offset, Err: err}
+
+	return d.err
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// lineReader counts the lines of the data read from r.
+type lineReader struct {
+	r        io.Reader
+	offset   int64
+	newlines []int64 // the offsets of the newlines not passed yet
+	passed   int     // the number of the newlines passed by line
+}
+
+func (l *lineReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+
+	for i := bytes.IndexByte(p[:n], '\n'); i >= 0; {
+		l.newlines = append(l.newlines, l.offset+int64(i))
+
+		next := bytes.IndexByte(p[i+1:n], '\n')
+		if next < 0 {
+			break
+		}
+
+		i += next + 1
+	}
+
+	l.offset += int64(n)
+
+	return n, err
+}
+
+// line returns the line of the offset. The offsets must not decrease between the calls.
+func (l *lineReader) line(offset int64) int {
+	i := 0
+	for i < len(l.newlines) && l.newlines[i] < offset {
+		i++
+	}
+
+	l.passed += i
+	l.newlines = l.newlines[i:]
+
+	return l.passed + 1
+}
@@ -0,0 +1,119 @@
+package poly_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+func TestDecoder(t *testing.T) {
+	expected := []IsItemValue{ItemValue1{}, ItemValue2{Key: "k"}, nil, ItemValue2{Key2: "k2"}}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name: "lines",
+			input: `{"type":"item-value-1"}
+{"type":"item-value-2","key":"k"}
+null
+{"type":"item-value-2","key2":"k2"}
+`,
+		},
+		{
+			name:  "array",
+			input: ` [{"type":"item-value-1"}, {"type":"item-value-2","key":"k"}, null, {"type":"item-value-2","key2":"k2"}] `,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := poly.NewDecoder[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](strings.NewReader(tt.input))
+
+			var values []IsItemValue
+
+			for dec.Next() {
+				values = append(values, dec.Value())
+			}
+
+			if err := dec.Err(); err != nil {
+				t.Fatalf("decoding error: %v", err)
+			}
+
+			if !reflect.DeepEqual(expected, values) {
+				t.Fatalf("expected %#v, got %#v", expected, values)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "  \n", "[]"} {
+		dec := poly.NewDecoder[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](strings.NewReader(input))
+
+		if _, err := dec.Decode(); !errors.Is(err, io.EOF) {
+			t.Fatalf("expected EOF for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestDecoder_errors(t *testing.T) {
+	t.Run("record", func(t *testing.T) {
+		input := "{\"type\":\"item-value-1\"}\n\n  {\"type\":\"item-value-unknown\"}\n{\"type\":\"item-value-2\"}\n"
+
+		dec := poly.NewDecoder[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](strings.NewReader(input))
+
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		_, err := dec.Decode()
+
+		var decodeErr *poly.DecodeError
+		if !errors.As(err, &decodeErr) || decodeErr.Index != 1 || decodeErr.Line != 3 || decodeErr.Offset != 27 {
+			t.Fatalf("expected error of record 1 at line 3, offset 27, got %v", err)
+		}
+
+		if !strings.Contains(err.Error(), "poly: unknown TypeName item-value-unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+
+		// the following records can still be decoded
+		if value, err := dec.Decode(); err != nil || value != (ItemValue2{}) {
+			t.Fatalf("expected ItemValue2, got %#v, %v", value, err)
+		}
+	})
+
+	t.Run("syntax", func(t *testing.T) {
+		input := "[\n{\"type\":\"item-value-1\"},\n{\"type\":}\n]"
+
+		dec := poly.NewDecoder[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](strings.NewReader(input))
+
+		for dec.Next() {
+		}
+
+		var decodeErr *poly.DecodeError
+		if err := dec.Err(); !errors.As(err, &decodeErr) || decodeErr.Index != 1 || decodeErr.Line != 3 {
+			t.Fatalf("expected error of record 1 at line 3, got %v", err)
+		}
+
+		if _, err := dec.Decode(); !errors.As(err, &decodeErr) {
+			t.Fatalf("expected the same error, got %v", err)
+		}
+	})
+
+	t.Run("unterminated array", func(t *testing.T) {
+		dec := poly.NewDecoder[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](strings.NewReader(`[{"type":"item-value-1"}`))
+
+		for dec.Next() {
+		}
+
+		var decodeErr *poly.DecodeError
+		if err := dec.Err(); !errors.As(err, &decodeErr) || decodeErr.Index != 1 {
+			t.Fatalf("expected error of record 1, got %v", err)
+		}
+	})
+}
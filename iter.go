@@ -0,0 +1,38 @@
+//go:build go1.23
+
+package poly
+
+import (
+	"errors"
+	"io"
+	"iter"
+)
+
+// All returns an iterator over the types listed by T.
+func All[T Types]() iter.Seq[Type] {
+	return func(yield func(Type) bool) {
+		for _, typ := range indexTypes[T]().types {
+			if !yield(typ) {
+				return
+			}
+		}
+	}
+}
+
+// All returns an iterator over the records of the stream along with their errors, see Decode.
+// The iteration continues after the errors of the records which can be skipped,
+// and stops at the end of the stream or after any other error.
+func (d *Decoder[I, T]) All() iter.Seq2[I, error] {
+	return func(yield func(I, error) bool) {
+		for {
+			value, err := d.Decode()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+
+			if !yield(value, err) || (err != nil && d.err != nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+//go:build go1.23
+
+package poly_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+func TestAll(t *testing.T) {
+	var names []string
+
+	for typ := range poly.All[poly.Types3[ItemValue1, ItemValue2, ItemMap]]() {
+		names = append(names, typ.Name)
+
+		if typ.Name == "item-value-2" {
+			break
+		}
+	}
+
+	if expected := []string{"item-value-1", "item-value-2"}; !reflect.DeepEqual(expected, names) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestDecoder_All(t *testing.T) {
+	input := `{"type":"item-value-1"}
+{"type":"item-value-unknown"}
+{"type":"item-value-2","key":"k"}
+{"type":
+{"type":"item-value-1"}
+`
+
+	dec := poly.NewDecoder[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](strings.NewReader(input))
+
+	var (
+		values []IsItemValue
+		lines  []int
+	)
+
+	for value, err := range dec.All() {
+		var decodeErr *poly.DecodeError
+		if errors.As(err, &decodeErr) {
+			lines = append(lines, decodeErr.Line)
+
+			continue
+		}
+
+		values = append(values, value)
+	}
+
+	if expected := []IsItemValue{ItemValue1{}, ItemValue2{Key: "k"}}; !reflect.DeepEqual(expected, values) {
+		t.Fatalf("expected %#v, got %#v", expected, values)
+	}
+
+	// the syntax error at line 4 stops the iteration
+	if expected := []int{2, 4}; !reflect.DeepEqual(expected, lines) {
+		t.Fatalf("expected errors at lines %v, got %v", expected, lines)
+	}
+}
@@ -0,0 +1,130 @@
+package poly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Lazy is like Poly, but it defers decoding of the underlying value until it is accessed.
+// UnmarshalJSON only extracts the discriminator and keeps the raw JSON, so the type of the value
+// is available cheaply via TypeName and Type, and the value itself is decoded on the first call to Get.
+// MarshalJSON returns the raw JSON as is until the value is replaced with Set, or until Get returns
+// a pointer, which can be used to modify the value.
+type Lazy[I any, T Types] struct {
+	typ     Type
+	raw     []byte
+	poly    Poly[I, T]
+	pending bool // the raw JSON is not decoded yet
+}
+
+// NewLazy creates a new Lazy holding the value.
+func NewLazy[I any, T Types](value I) Lazy[I, T] {
+	var l Lazy[I, T]
+
+	l.Set(value)
+
+	return l
+}
+
+// TypeName returns the TypeName of the value, or an empty string if there is no value.
+func (l *Lazy[I, T]) TypeName() string {
+	return l.typ.Name
+}
+
+// Type returns the Type listed by T for the value, or a zero Type if there is no value.
+func (l *Lazy[I, T]) Type() Type {
+	return l.typ
+}
+
+// Get returns the value, decoding it from the raw JSON on the first call.
+func (l *Lazy[I, T]) Get() (I, error) {
+	if l.pending {
+		if err := json.Unmarshal(l.raw, &l.poly); err != nil {
+			return l.poly.Value, err
+		}
+
+		l.pending = false
+	}
+
+	// the value can be modified via the pointer, so the raw JSON cannot be reused anymore
+	if reflect.ValueOf(l.poly.Value).Kind() == reflect.Pointer {
+		l.raw = nil
+	}
+
+	return l.poly.Value, nil
+}
+
+// Set replaces the value.
+func (l *Lazy[I, T]) Set(value I) {
+	l.poly.Value = value
+	l.raw = nil
+	l.pending = false
+	l.typ = Type{}
+
+	if typ, ok, err := l.poly.ResolveType(); err == nil && ok {
+		l.typ = typ
+	}
+}
+
+// MarshalJSON implements the json.Marshaler interface for Lazy.
+// It returns the raw JSON if the value has not been modified, and marshals the value like Poly otherwise.
+func (l Lazy[I, T]) MarshalJSON() ([]byte, error) {
+	if l.raw != nil {
+		return l.raw, nil
+	}
+
+	return l.poly.MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for Lazy.
+// It only extracts the discriminator 'type' and keeps the raw JSON to decode the value later.
+// If the discriminator is missing or matches the type of the current value, like with Poly,
+// the current value is decoded and patched right away.
+func (l *Lazy[I, T]) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		*l = Lazy[I, T]{}
+
+		return nil
+	}
+
+	var discriminator struct {
+		TypeName string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &discriminator); err != nil {
+		return fmt.Errorf("poly: cannot unmarshal discriminator 'type': %w", err)
+	}
+
+	if l.typ.Name != "" && (discriminator.TypeName == "" || discriminator.TypeName == l.typ.Name) {
+		if _, err := l.Get(); err != nil {
+			return err
+		}
+
+		if err := json.Unmarshal(data, &l.poly); err != nil {
+			return err
+		}
+
+		l.raw = nil
+
+		return nil
+	}
+
+	typ, err := lookupDiscriminator[T](discriminator.TypeName)
+	if err != nil {
+		return err
+	}
+
+	if err := checkTypeChange[T](l.typ.Name, typ.Name); err != nil {
+		return err
+	}
+
+	*l = Lazy[I, T]{
+		typ:     typ,
+		raw:     append([]byte(nil), data...),
+		pending: true,
+	}
+
+	return nil
+}
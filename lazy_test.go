@@ -0,0 +1,180 @@
+package poly_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+type LazyItemPointer = poly.Lazy[IsItemPointer, poly.Types2[*ItemPointer1, *ItemPointer2]]
+
+type LazyItemValue = poly.Lazy[IsItemValue, poly.Types2[ItemValue1, ItemValue2]]
+
+func TestLazy(t *testing.T) {
+	t.Run("raw", func(t *testing.T) {
+		bIn := []byte(`{"key2":"k2","type":"item-value-2","unknown":[1,2],"key":"k"}`)
+
+		var item LazyItemValue
+
+		if err := json.Unmarshal(bIn, &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if item.TypeName() != "item-value-2" || item.Type().Name != "item-value-2" {
+			t.Fatalf("expected item-value-2, got %s", item.TypeName())
+		}
+
+		value, err := item.Get()
+		if err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if got, ok := value.(ItemValue2); !ok || got.Key != "k" || got.Key2 != "k2" {
+			t.Fatalf("expected ItemValue2, got %#v", value)
+		}
+
+		bOut, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if !bytes.Equal(bIn, bOut) {
+			t.Fatalf("expected %s, got %s", bIn, bOut)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		var item LazyItemValue
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-1"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		item.Set(ItemValue2{Key: "k"})
+
+		if item.TypeName() != "item-value-2" {
+			t.Fatalf("expected item-value-2, got %s", item.TypeName())
+		}
+
+		bOut, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if expected := []byte(`{"type":"item-value-2","key":"k"}`); !bytes.Equal(expected, bOut) {
+			t.Fatalf("expected %s, got %s", expected, bOut)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		var item LazyItemPointer
+
+		if err := json.Unmarshal([]byte(`{"type":"item-pointer-2", "key":"k"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		value, err := item.Get()
+		if err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		value.(*ItemPointer2).Key = "k2" //nolint:forcetypeassert
+
+		bOut, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if expected := []byte(`{"type":"item-pointer-2","key":"k2"}`); !bytes.Equal(expected, bOut) {
+			t.Fatalf("expected %s, got %s", expected, bOut)
+		}
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		item := poly.NewLazy[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](ItemValue2{Key: "k"})
+
+		if err := json.Unmarshal([]byte(`{"key2":"k2"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		value, err := item.Get()
+		if err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if got, ok := value.(ItemValue2); !ok || got.Key != "k" || got.Key2 != "k2" {
+			t.Fatalf("expected patched ItemValue2, got %#v", value)
+		}
+	})
+
+	t.Run("patch same type", func(t *testing.T) {
+		item := poly.NewLazy[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](ItemValue2{Key: "k"})
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-2","key2":"k2"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		value, err := item.Get()
+		if err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if got, ok := value.(ItemValue2); !ok || got.Key != "k" || got.Key2 != "k2" {
+			t.Fatalf("expected patched ItemValue2, got %#v", value)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		item := poly.NewLazy[IsItemValue, poly.Types2[ItemValue1, ItemValue2]](ItemValue1{})
+
+		if err := json.Unmarshal([]byte(`null`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if value, err := item.Get(); value != nil || err != nil || item.TypeName() != "" {
+			t.Fatalf("expected nil, got %#v, %v", value, err)
+		}
+
+		bOut, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if !bytes.Equal([]byte("null"), bOut) {
+			t.Fatalf("expected null, got %s", bOut)
+		}
+	})
+
+	t.Run("deferred error", func(t *testing.T) {
+		var item LazyItemValue
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-2","key":1}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if _, err := item.Get(); err == nil {
+			t.Fatalf("expected decoding error")
+		}
+	})
+
+	t.Run("missing discriminator", func(t *testing.T) {
+		var item LazyItemValue
+
+		err := json.Unmarshal([]byte(`{}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "missing discriminator") {
+			t.Fatalf("expected missing discriminator error, got %v", err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		var item LazyItemValue
+
+		err := json.Unmarshal([]byte(`{"type":"item-value-unknown"}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-value-unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
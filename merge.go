@@ -0,0 +1,172 @@
+package poly
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// MergePatch applies the JSON merge patch (RFC 7396) to the value of the Poly.
+// Unlike unmarshaling into a Poly, null members of the patch remove the corresponding members
+// and nested objects are merged recursively. The discriminator 'type' can be patched as well,
+// which changes the type of the value unless StrictPatch forbids it.
+// The value is replaced with a new one decoded from the merged JSON.
+func MergePatch[I any, T Types](p *Poly[I, T], patch []byte) error {
+	doc, err := p.decodeAny()
+	if err != nil {
+		return err
+	}
+
+	patchDoc, err := decodeAny(patch)
+	if err != nil {
+		return fmt.Errorf("poly: cannot decode merge patch: %w", err)
+	}
+
+	return p.replaceWith(mergePatch(doc, patchDoc))
+}
+
+// CreateMergePatch returns the JSON merge patch (RFC 7396) that turns from into to when applied with MergePatch.
+// As merge patches use null to remove members, null members of to cannot be represented and are removed.
+func CreateMergePatch[I any, T Types](from, to Poly[I, T]) ([]byte, error) {
+	fromDoc, err := from.decodeAny()
+	if err != nil {
+		return nil, err
+	}
+
+	toDoc, err := to.decodeAny()
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := json.Marshal(createMergePatch(fromDoc, toDoc))
+	if err != nil {
+		return nil, fmt.Errorf("poly: cannot encode merge patch: %w", err)
+	}
+
+	return patch, nil
+}
+
+// decodeAny marshals the Poly and decodes the JSON to generic values, see decodeAny.
+func (p Poly[I, T]) decodeAny() (any, error) {
+	data, err := p.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := decodeAny(data)
+	if err != nil {
+		return nil, fmt.Errorf("poly: cannot decode value: %w", err)
+	}
+
+	return doc, nil
+}
+
+// replaceWith replaces the value of the Poly with the one unmarshaled from the generic JSON values in doc.
+// The value is left untouched if the new one cannot be unmarshaled or StrictPatch forbids its type.
+func (p *Poly[I, T]) replaceWith(doc any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("poly: cannot encode patched value: %w", err)
+	}
+
+	var result Poly[I, T]
+
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+
+	from, fromOK, err := p.ResolveType()
+	if err != nil {
+		return err
+	}
+
+	to, toOK, err := result.ResolveType()
+	if err != nil {
+		return err
+	}
+
+	if fromOK && toOK {
+		if err := checkTypeChange[T](from.Name, to.Name); err != nil {
+			return err
+		}
+	}
+
+	p.Value = result.Value
+
+	return nil
+}
+
+// decodeAny decodes JSON to generic values keeping the numbers as json.Number to preserve their precision.
+func decodeAny(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// mergePatch implements the MergePatch function of RFC 7396.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+
+			continue
+		}
+
+		targetObj[name] = mergePatch(targetObj[name], value)
+	}
+
+	return targetObj
+}
+
+// createMergePatch returns a merge patch that turns from into to.
+func createMergePatch(from, to any) any {
+	fromObj, fromOK := from.(map[string]any)
+	toObj, toOK := to.(map[string]any)
+
+	if !fromOK || !toOK {
+		return to
+	}
+
+	patch := map[string]any{}
+
+	for name := range fromObj {
+		if _, ok := toObj[name]; !ok {
+			patch[name] = nil
+		}
+	}
+
+	for name, toValue := range toObj {
+		fromValue, ok := fromObj[name]
+
+		switch {
+		case toValue == nil:
+			if ok {
+				patch[name] = nil
+			}
+		case !ok:
+			patch[name] = createMergePatch(nil, toValue)
+		case !reflect.DeepEqual(fromValue, toValue):
+			patch[name] = createMergePatch(fromValue, toValue)
+		}
+	}
+
+	return patch
+}
@@ -0,0 +1,161 @@
+package poly_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+type ItemMap struct {
+	Key    string            `json:"key,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Count  int64             `json:"count,omitempty"`
+}
+
+func (ItemMap) IsItemValue() {}
+
+func (ItemMap) TypeName() string {
+	return "item-map"
+}
+
+type ItemMapValue = poly.Poly[IsItemValue, poly.Types3[ItemValue1, ItemValue2, ItemMap]]
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    IsItemValue
+		patch    string
+		expected IsItemValue
+	}{
+		{
+			name:     "merge",
+			value:    ItemMap{Key: "k", Labels: map[string]string{"a": "1", "b": "2"}},
+			patch:    `{"labels":{"b":null,"c":"3"},"count":9007199254740993}`,
+			expected: ItemMap{Key: "k", Labels: map[string]string{"a": "1", "c": "3"}, Count: 9007199254740993},
+		},
+		{
+			name:     "remove",
+			value:    ItemMap{Key: "k", Labels: map[string]string{"a": "1"}},
+			patch:    `{"key":null,"labels":null}`,
+			expected: ItemMap{},
+		},
+		{
+			name:     "type change",
+			value:    ItemValue2{Key: "k", Key2: "k2"},
+			patch:    `{"type":"item-map","key2":null}`,
+			expected: ItemMap{Key: "k"},
+		},
+		{
+			name:     "no value",
+			value:    nil,
+			patch:    `{"type":"item-value-2","key":"k"}`,
+			expected: ItemValue2{Key: "k"},
+		},
+		{
+			name:     "null",
+			value:    ItemValue2{Key: "k"},
+			patch:    `null`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := ItemMapValue{Value: tt.value}
+
+			if err := poly.MergePatch(&item, []byte(tt.patch)); err != nil {
+				t.Fatalf("merge patch error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.expected, item.Value) {
+				t.Fatalf("expected %#v, got %#v", tt.expected, item.Value)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		item := ItemMapValue{Value: ItemValue2{Key: "k"}}
+
+		err := poly.MergePatch(&item, []byte(`{"type":"item-value-unknown"}`))
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-value-unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+
+		if item.Value != (ItemValue2{Key: "k"}) {
+			t.Fatalf("expected untouched value, got %#v", item.Value)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		item := poly.Poly[IsItemValue, poly.StrictPatch[poly.Types2[ItemValue1, ItemValue2]]]{Value: ItemValue2{}}
+
+		var typeChangeErr *poly.TypeChangeError
+		if err := poly.MergePatch(&item, []byte(`{"type":"item-value-1"}`)); !errors.As(err, &typeChangeErr) {
+			t.Fatalf("expected TypeChangeError, got %v", err)
+		}
+
+		if item.Value != (ItemValue2{}) {
+			t.Fatalf("expected untouched value, got %#v", item.Value)
+		}
+	})
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     IsItemValue
+		to       IsItemValue
+		expected string
+	}{
+		{
+			name:     "equal",
+			from:     ItemMap{Key: "k", Labels: map[string]string{"a": "1"}},
+			to:       ItemMap{Key: "k", Labels: map[string]string{"a": "1"}},
+			expected: `{}`,
+		},
+		{
+			name:     "change",
+			from:     ItemMap{Key: "k", Labels: map[string]string{"a": "1", "b": "2"}},
+			to:       ItemMap{Labels: map[string]string{"a": "1", "c": "3"}, Count: 1},
+			expected: `{"count":1,"key":null,"labels":{"b":null,"c":"3"}}`,
+		},
+		{
+			name:     "type change",
+			from:     ItemValue2{Key: "k", Key2: "k2"},
+			to:       ItemMap{Key: "k"},
+			expected: `{"key2":null,"type":"item-map"}`,
+		},
+		{
+			name:     "to null",
+			from:     ItemValue2{Key: "k"},
+			to:       nil,
+			expected: `null`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := ItemMapValue{Value: tt.from}, ItemMapValue{Value: tt.to}
+
+			patch, err := poly.CreateMergePatch(from, to)
+			if err != nil {
+				t.Fatalf("create merge patch error: %v", err)
+			}
+
+			if string(patch) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, patch)
+			}
+
+			if err := poly.MergePatch(&from, patch); err != nil {
+				t.Fatalf("merge patch error: %v", err)
+			}
+
+			if !reflect.DeepEqual(to.Value, from.Value) {
+				t.Fatalf("expected %#v, got %#v", to.Value, from.Value)
+			}
+		})
+	}
+}
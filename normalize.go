@@ -0,0 +1,138 @@
+package poly
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// AsPointers wraps a list of types to always decode the values as pointers, e.g. *ActionDeepLink
+// even if ActionDeepLink is listed, including when an existing value is patched:
+//
+//	type Action = poly.Poly[IsAction, poly.AsPointers[poly.Types2[ActionDismiss, ActionDeepLink]]]
+//
+// A pointer held by the Poly is patched in place.
+type AsPointers[T Types] struct {
+	types T
+}
+
+func (s AsPointers[T]) Types() []Type {
+	return s.types.Types()
+}
+
+func (AsPointers[T]) normalization() normalization {
+	return asPointers
+}
+
+func (s AsPointers[T]) unwrapTypes() Types {
+	return s.types
+}
+
+// AsValues wraps a list of types to always decode the values as non-pointers, e.g. ActionDeepLink
+// even if *ActionDeepLink is listed, including when an existing value is patched.
+// Decoding fails if the non-pointer type does not implement the interface of the Poly.
+type AsValues[T Types] struct {
+	types T
+}
+
+func (s AsValues[T]) Types() []Type {
+	return s.types.Types()
+}
+
+func (AsValues[T]) normalization() normalization {
+	return asValues
+}
+
+func (s AsValues[T]) unwrapTypes() Types {
+	return s.types
+}
+
+// AsDeclared wraps a list of types to always decode the values as the listed types, including when
+// an existing value of the pointer or non-pointer counterpart of a listed type is patched.
+// Without any of AsDeclared, AsPointers and AsValues, a patched value keeps its form.
+type AsDeclared[T Types] struct {
+	types T
+}
+
+func (s AsDeclared[T]) Types() []Type {
+	return s.types.Types()
+}
+
+func (AsDeclared[T]) normalization() normalization {
+	return asDeclared
+}
+
+func (s AsDeclared[T]) unwrapTypes() Types {
+	return s.types
+}
+
+// normalization is the form of the decoded values.
+type normalization int
+
+const (
+	asIs normalization = iota
+	asDeclared
+	asPointers
+	asValues
+)
+
+type normalizer interface {
+	normalization() normalization
+}
+
+// normalizationOf returns the normalization of the values of a Poly with the types T.
+func normalizationOf[T Types]() normalization {
+	n, ok := typesOption[normalizer, T]()
+	if !ok {
+		return asIs
+	}
+
+	return n.normalization()
+}
+
+func isPointer(value any) bool {
+	reflectValue := reflect.ValueOf(value)
+
+	return reflectValue.Kind() == reflect.Pointer && !reflectValue.IsNil()
+}
+
+// normalize converts the value of the listed type typ, or of its pointer or non-pointer counterpart,
+// to the form required by the normalization.
+func normalize[I any](n normalization, typ Type, value I) (I, error) {
+	if n == asIs {
+		return value, nil
+	}
+
+	base := typ.ReflectType
+	if base.Kind() == reflect.Pointer {
+		base = base.Elem()
+	}
+
+	pointer := n == asPointers || n == asDeclared && typ.ReflectType.Kind() == reflect.Pointer
+
+	reflectValue := reflect.ValueOf(value)
+
+	switch {
+	case !reflectValue.IsValid():
+		return value, nil
+	case pointer && reflectValue.Type() == base:
+		ptr := reflect.New(base)
+		ptr.Elem().Set(reflectValue)
+
+		reflectValue = ptr
+	case !pointer && reflectValue.Type() == reflect.PointerTo(base):
+		if reflectValue.IsNil() {
+			reflectValue = reflect.Zero(base)
+		} else {
+			reflectValue = reflectValue.Elem()
+		}
+	default:
+		return value, nil
+	}
+
+	normalized, ok := reflectValue.Interface().(I)
+	if !ok {
+		return value, fmt.Errorf("poly: cannot use '%s' as I", reflectValue.Type())
+	}
+
+	return normalized, nil
+}
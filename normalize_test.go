@@ -0,0 +1,230 @@
+package poly_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+func TestAsPointers(t *testing.T) {
+	type Item = poly.Poly[IsItemValue, poly.AsPointers[poly.Types2[ItemValue1, ItemValue2]]]
+
+	t.Run("new", func(t *testing.T) {
+		var item Item
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-2","key":"k"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := item.Value.(*ItemValue2); !ok || got.Key != "k" {
+			t.Fatalf("expected *ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("patch pointer", func(t *testing.T) {
+		value := &ItemValue2{Key: "k", Key2: "k2"}
+		item := Item{Value: value}
+
+		if err := json.Unmarshal([]byte(`{"key":"k3"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if item.Value != value {
+			t.Fatalf("expected the pointer to be patched in place, got %#v", item.Value)
+		}
+
+		if expected := (ItemValue2{Key: "k3", Key2: "k2"}); *value != expected {
+			t.Fatalf("expected %#v, got %#v", expected, *value)
+		}
+	})
+
+	t.Run("patch value", func(t *testing.T) {
+		item := Item{Value: ItemValue2{Key: "k", Key2: "k2"}}
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-2","key":"k3"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if got, ok := item.Value.(*ItemValue2); !ok || *got != (ItemValue2{Key: "k3", Key2: "k2"}) {
+			t.Fatalf("expected *ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("body", func(t *testing.T) {
+		item := Item{Value: ItemValue2{Key: "k"}}
+
+		if err := item.UnmarshalBody("", 0, []byte(`{"key2":"k2"}`)); err != nil {
+			t.Fatalf("unmarshaling body error: %v", err)
+		}
+
+		if got, ok := item.Value.(*ItemValue2); !ok || *got != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected *ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("json patch", func(t *testing.T) {
+		item := Item{Value: ItemValue2{Key: "k"}}
+
+		if err := poly.ApplyPatch(&item, []byte(`[{"op":"add","path":"/key2","value":"k2"}]`)); err != nil {
+			t.Fatalf("patching error: %v", err)
+		}
+
+		if got, ok := item.Value.(*ItemValue2); !ok || *got != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected *ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("merge patch", func(t *testing.T) {
+		item := Item{Value: ItemValue2{Key: "k"}}
+
+		if err := poly.MergePatch(&item, []byte(`{"key2":"k2"}`)); err != nil {
+			t.Fatalf("patching error: %v", err)
+		}
+
+		if got, ok := item.Value.(*ItemValue2); !ok || *got != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected *ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("constructor", func(t *testing.T) {
+		item := poly.Poly[IsItemValue, poly.AsPointers[ItemValueTypes]]{Value: ItemValue2{Key: "k"}}
+
+		if err := json.Unmarshal([]byte(`{"key2":"k2"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if got, ok := item.Value.(*ItemValue2); !ok || *got != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected *ItemValue2, got %#v", item.Value)
+		}
+	})
+}
+
+func TestAsValues(t *testing.T) {
+	type Item = poly.Poly[IsItemValue, poly.AsValues[poly.Types2[ItemValue1, ItemValue2]]]
+
+	t.Run("new", func(t *testing.T) {
+		var item Item
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-2","key":"k"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemValue2); !ok || got.Key != "k" {
+			t.Fatalf("expected ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("patch pointer", func(t *testing.T) {
+		item := Item{Value: &ItemValue2{Key: "k"}}
+
+		if err := json.Unmarshal([]byte(`{"key2":"k2"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemValue2); !ok || got != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("patch value", func(t *testing.T) {
+		item := Item{Value: ItemValue2{Key: "k"}}
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-2","key2":"k2"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemValue2); !ok || got != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("json patch", func(t *testing.T) {
+		item := Item{Value: ItemValue2{Key: "k"}}
+
+		if err := poly.ApplyPatch(&item, []byte(`[{"op":"replace","path":"/key","value":"k2"}]`)); err != nil {
+			t.Fatalf("patching error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemValue2); !ok || got != (ItemValue2{Key: "k2"}) {
+			t.Fatalf("expected ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("pointer receivers", func(t *testing.T) {
+		var item poly.Poly[IsItemPointer, poly.AsValues[poly.Types2[*ItemPointer1, *ItemPointer2]]]
+
+		err := json.Unmarshal([]byte(`{"type":"item-pointer-2"}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: cannot use 'poly_test.ItemPointer2' as I") {
+			t.Fatalf("expected cannot use error, got %v", err)
+		}
+
+		if item.Value != nil {
+			t.Fatalf("expected no value, got %#v", item.Value)
+		}
+	})
+}
+
+func TestAsDeclared(t *testing.T) {
+	type Item = poly.Poly[IsItemValue, poly.AsDeclared[poly.Types2[ItemValue1, ItemValue2]]]
+
+	t.Run("patch pointer", func(t *testing.T) {
+		value := &ItemValue2{Key: "k"}
+		item := Item{Value: value}
+
+		if err := json.Unmarshal([]byte(`{"key2":"k2"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemValue2); !ok || got != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected ItemValue2, got %#v", item.Value)
+		}
+
+		if expected := (ItemValue2{Key: "k"}); *value != expected {
+			t.Fatalf("expected the pointer to be untouched, got %#v", *value)
+		}
+	})
+
+	t.Run("merge patch", func(t *testing.T) {
+		item := Item{Value: &ItemValue2{Key: "k"}}
+
+		if err := poly.MergePatch(&item, []byte(`{"key":null,"key2":"k2"}`)); err != nil {
+			t.Fatalf("patching error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemValue2); !ok || got != (ItemValue2{Key2: "k2"}) {
+			t.Fatalf("expected ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("as is", func(t *testing.T) {
+		item := ItemValue{Value: &ItemValue2{Key: "k"}}
+
+		if err := json.Unmarshal([]byte(`{"key2":"k2"}`), &item); err != nil {
+			t.Fatalf("unmarshaling patch error: %v", err)
+		}
+
+		if _, ok := item.Value.(*ItemValue2); !ok {
+			t.Fatalf("expected *ItemValue2 without normalization, got %#v", item.Value)
+		}
+	})
+}
+
+func TestAsPointers_StrictPatch(t *testing.T) {
+	for name, item := range map[string]interface {
+		json.Unmarshaler
+	}{
+		"outer": &poly.Poly[IsItemValue, poly.StrictPatch[poly.AsPointers[poly.Types2[ItemValue1, ItemValue2]]]]{Value: ItemValue1{}},
+		"inner": &poly.Poly[IsItemValue, poly.AsPointers[poly.StrictPatch[poly.Types2[ItemValue1, ItemValue2]]]]{Value: ItemValue1{}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var changeErr *poly.TypeChangeError
+
+			if err := json.Unmarshal([]byte(`{"type":"item-value-2"}`), item); !errors.As(err, &changeErr) {
+				t.Fatalf("expected TypeChangeError, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+// Package openapi generates OpenAPI 3.1 component schemas for poly.Poly types.
+//
+// Every Poly type becomes a component with a oneOf over its variants and a discriminator
+// with a mapping from TypeName to the variant component. Every variant becomes a component
+// describing its JSON object, including the 'type' discriminator property.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+const discriminatorProperty = "type"
+
+// versionProperty is the member holding the version of poly.Versioned variants.
+const versionProperty = "version"
+
+const refPrefix = "#/components/schemas/"
+
+// Components holds the reusable schemas of an OpenAPI document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema is the subset of the OpenAPI 3.1 Schema Object used to describe Poly types.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Const                any                `json:"const,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Discriminator        *Discriminator     `json:"discriminator,omitempty"`
+}
+
+// Discriminator is the OpenAPI Discriminator Object.
+type Discriminator struct {
+	PropertyName string            `json:"propertyName"`
+	Mapping      map[string]string `json:"mapping,omitempty"`
+}
+
+type polyTypes interface {
+	PolyTypes() []poly.Type
+}
+
+// The reflect.Types which are checked to describe a type; they are immutable.
+var ( //nolint:gochecknoglobals
+	polyTypesType     = reflect.TypeOf((*polyTypes)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// componentKey identifies a component: the same struct is described differently
+// when it is used as a variant of a Poly (with the discriminator) and as a plain object.
+type componentKey struct {
+	reflectType reflect.Type
+	typeName    string
+}
+
+// Generator collects component schemas for Poly types and the types they reference.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[componentKey]string
+}
+
+// NewGenerator creates a new Generator without any components.
+func NewGenerator() *Generator {
+	return &Generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[componentKey]string),
+	}
+}
+
+// Add adds a component named name for the Poly type of v, along with components of its variants.
+// Variant components are named after the Go types of the variants; if the name is already taken,
+// the TypeName of the variant is appended to it.
+func (g *Generator) Add(name string, v any) error {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || !rt.Implements(polyTypesType) {
+		return fmt.Errorf("openapi: %T is not a poly.Poly", v)
+	}
+
+	if current, ok := g.names[componentKey{reflectType: rt}]; ok {
+		if current != name {
+			return fmt.Errorf("openapi: %s is already added as %s", rt, current)
+		}
+
+		return nil
+	}
+
+	if _, ok := g.schemas[name]; ok {
+		return fmt.Errorf("openapi: component %s already exists", name)
+	}
+
+	_, err := g.addPoly(name, rt)
+
+	return err
+}
+
+// Components returns all the components collected so far.
+func (g *Generator) Components() Components {
+	schemas := make(map[string]*Schema, len(g.schemas))
+	for name, schema := range g.schemas {
+		schemas[name] = schema
+	}
+
+	return Components{Schemas: schemas}
+}
+
+func (g *Generator) addPoly(name string, rt reflect.Type) (*Schema, error) {
+	key := componentKey{reflectType: rt}
+
+	schema := &Schema{
+		Discriminator: &Discriminator{
+			PropertyName: discriminatorProperty,
+			Mapping:      make(map[string]string),
+		},
+	}
+
+	g.names[key] = name
+	g.schemas[name] = schema
+
+	types := reflect.Zero(rt).Interface().(polyTypes).PolyTypes() //nolint:forcetypeassert
+
+	for _, typ := range types {
+		if _, ok := schema.Discriminator.Mapping[typ.Name]; ok {
+			return nil, fmt.Errorf("openapi: duplicate TypeName %s in %s", typ.Name, rt)
+		}
+
+		ref, err := g.variantRef(typ)
+		if err != nil {
+			return nil, err
+		}
+
+		schema.OneOf = append(schema.OneOf, ref)
+		schema.Discriminator.Mapping[typ.Name] = ref.Ref
+	}
+
+	return &Schema{Ref: refPrefix + name}, nil
+}
+
+func (g *Generator) variantRef(typ poly.Type) (*Schema, error) {
+	rt := typ.ReflectType
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("openapi: variant %s of TypeName %s is not a struct", typ.ReflectType, typ.Name)
+	}
+
+	key := componentKey{reflectType: rt, typeName: typ.Name}
+	if name, ok := g.names[key]; ok {
+		return &Schema{Ref: refPrefix + name}, nil
+	}
+
+	name := g.freeName(sanitize(rt.Name()), sanitize(rt.Name())+pascal(typ.Name))
+	schema := &Schema{}
+
+	g.names[key] = name
+	g.schemas[name] = schema
+
+	object, err := g.structSchema(rt)
+	if err != nil {
+		return nil, err
+	}
+
+	*schema = *object
+	schema.Properties[discriminatorProperty] = &Schema{Type: "string", Const: typ.Name}
+	schema.Required = append([]string{discriminatorProperty}, schema.Required...)
+
+	// the version must be sent, as a missing one is treated as version 1 and migrated
+	zero := reflect.Zero(typ.ReflectType)
+	if typ.ReflectType.Kind() == reflect.Pointer {
+		// a nil pointer would panic in PolyVersion declared with a value receiver
+		zero = reflect.New(typ.ReflectType.Elem())
+	}
+
+	if versioned, ok := zero.Interface().(poly.Versioned); ok {
+		schema.Properties[versionProperty] = &Schema{Type: "integer", Const: versioned.PolyVersion()}
+		schema.Required = append([]string{discriminatorProperty, versionProperty}, schema.Required[1:]...)
+	}
+
+	return &Schema{Ref: refPrefix + name}, nil
+}
+
+func (g *Generator) structRef(rt reflect.Type) (*Schema, error) {
+	key := componentKey{reflectType: rt}
+	if name, ok := g.names[key]; ok {
+		return &Schema{Ref: refPrefix + name}, nil
+	}
+
+	name := g.freeName(sanitize(rt.Name()))
+	schema := &Schema{}
+
+	g.names[key] = name
+	g.schemas[name] = schema
+
+	object, err := g.structSchema(rt)
+	if err != nil {
+		return nil, err
+	}
+
+	*schema = *object
+
+	return &Schema{Ref: refPrefix + name}, nil
+}
+
+func (g *Generator) schemaOf(rt reflect.Type) (*Schema, error) {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	switch {
+	case rt.Implements(polyTypesType):
+		if name, ok := g.names[componentKey{reflectType: rt}]; ok {
+			return &Schema{Ref: refPrefix + name}, nil
+		}
+
+		return g.addPoly(g.freeName(polyName(rt)), rt)
+	case rt == timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	case rt.Implements(jsonMarshalerType) || reflect.PointerTo(rt).Implements(jsonMarshalerType):
+		return &Schema{}, nil
+	case rt.Implements(textMarshalerType) || reflect.PointerTo(rt).Implements(textMarshalerType):
+		return &Schema{Type: "string"}, nil
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Slice, reflect.Array:
+		if rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+
+		items, err := g.schemaOf(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if rt.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("openapi: unsupported map key type %s", rt.Key())
+		}
+
+		values, err := g.schemaOf(rt.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if rt.Name() == "" {
+			return g.structSchema(rt)
+		}
+
+		return g.structRef(rt)
+	case reflect.Interface:
+		return &Schema{}, nil
+	default:
+		return nil, fmt.Errorf("openapi: unsupported type %s", rt)
+	}
+}
+
+func (g *Generator) structSchema(rt reflect.Type) (*Schema, error) {
+	schema := &Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	if err := g.addFields(schema, rt, false); err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// addFields adds the properties of the JSON object encoded from rt to schema.
+// Fields of embedded structs do not override the already known properties.
+func (g *Generator) addFields(schema *Schema, rt reflect.Type, embedded bool) error {
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				if err := g.addFields(schema, ft, true); err != nil {
+					return err
+				}
+
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if _, ok := schema.Properties[name]; ok && embedded {
+			continue
+		}
+
+		fieldSchema, err := g.schemaOf(field.Type)
+		if err != nil {
+			return fmt.Errorf("openapi: field %s of %s: %w", field.Name, rt, err)
+		}
+
+		if _, ok := schema.Properties[name]; !ok && isRequired(field.Type, opts) {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = fieldSchema
+	}
+
+	return nil
+}
+
+func isRequired(rt reflect.Type, opts string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == "omitempty" || opt == "omitzero" {
+			return false
+		}
+	}
+
+	return rt.Kind() != reflect.Pointer
+}
+
+// freeName returns the first of candidates that is not used by any component yet.
+// If all of them are used, a numeric suffix is appended to the last one.
+func (g *Generator) freeName(candidates ...string) string {
+	for _, name := range candidates {
+		if _, ok := g.schemas[name]; !ok && name != "" {
+			return name
+		}
+	}
+
+	last := candidates[len(candidates)-1]
+	for i := 2; ; i++ {
+		name := last + strconv.Itoa(i)
+		if _, ok := g.schemas[name]; !ok {
+			return name
+		}
+	}
+}
+
+// polyName returns the default name of a Poly component, derived from its interface type.
+func polyName(rt reflect.Type) string {
+	if field, ok := rt.FieldByName("Value"); ok && field.Type.Name() != "" {
+		return sanitize(field.Type.Name())
+	}
+
+	return "Poly"
+}
+
+// isInvalidNameRune reports whether r cannot be used in a component name.
+func isInvalidNameRune(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_')
+}
+
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if isInvalidNameRune(r) {
+			return '_'
+		}
+
+		return r
+	}, name)
+}
+
+// pascal converts a TypeName like "deep-link" to "DeepLink".
+func pascal(typeName string) string {
+	var b strings.Builder
+
+	for _, part := range strings.FieldsFunc(typeName, isInvalidNameRune) {
+		for _, word := range strings.FieldsFunc(part, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(word[:1]))
+			b.WriteString(word[1:])
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,368 @@
+package openapi_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/openapi"
+)
+
+type IsAction interface {
+	IsAction()
+}
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) IsAction() {}
+
+func (ActionDismiss) TypeName() string {
+	return "dismiss"
+}
+
+type ActionDeepLink struct {
+	URL     string    `json:"url"`
+	Title   string    `json:"title,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+func (*ActionDeepLink) IsAction() {}
+
+func (*ActionDeepLink) TypeName() string {
+	return "deep-link"
+}
+
+type ActionGroup struct {
+	Actions []Action `json:"actions"`
+	Primary *Action  `json:"primary"`
+}
+
+func (ActionGroup) IsAction() {}
+
+func (ActionGroup) TypeName() string {
+	return "group"
+}
+
+type Action = poly.Poly[IsAction, poly.Types3[ActionDismiss, *ActionDeepLink, ActionGroup]]
+
+type IsOther interface {
+	IsOther()
+}
+
+type OtherDismiss struct {
+	Reason string `json:"reason"`
+}
+
+func (OtherDismiss) IsOther() {}
+
+func (OtherDismiss) TypeName() string {
+	return "dismiss"
+}
+
+type ActionDismissAlias struct {
+	ActionDismiss
+	Tags map[string]int `json:"tags,omitempty"`
+}
+
+func (ActionDismissAlias) IsOther() {}
+
+func (ActionDismissAlias) TypeName() string {
+	return "dismiss-alias"
+}
+
+type Other = poly.Poly[IsOther, poly.Types2[OtherDismiss, ActionDismissAlias]]
+
+type Duplicated = poly.Poly[IsAction, poly.Types2[ActionDismiss, ActionDismiss]]
+
+func TestGenerator(t *testing.T) {
+	g := openapi.NewGenerator()
+
+	if err := g.Add("Action", Action{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := g.Add("Other", &Other{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := json.MarshalIndent(g.Components(), "", "  ")
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	expected := `{
+  "schemas": {
+    "Action": {
+      "oneOf": [
+        {
+          "$ref": "#/components/schemas/ActionDismiss"
+        },
+        {
+          "$ref": "#/components/schemas/ActionDeepLink"
+        },
+        {
+          "$ref": "#/components/schemas/ActionGroup"
+        }
+      ],
+      "discriminator": {
+        "propertyName": "type",
+        "mapping": {
+          "deep-link": "#/components/schemas/ActionDeepLink",
+          "dismiss": "#/components/schemas/ActionDismiss",
+          "group": "#/components/schemas/ActionGroup"
+        }
+      }
+    },
+    "ActionDeepLink": {
+      "type": "object",
+      "properties": {
+        "expires": {
+          "type": "string",
+          "format": "date-time"
+        },
+        "title": {
+          "type": "string"
+        },
+        "type": {
+          "type": "string",
+          "const": "deep-link"
+        },
+        "url": {
+          "type": "string"
+        }
+      },
+      "required": [
+        "type",
+        "url",
+        "expires"
+      ]
+    },
+    "ActionDismiss": {
+      "type": "object",
+      "properties": {
+        "type": {
+          "type": "string",
+          "const": "dismiss"
+        }
+      },
+      "required": [
+        "type"
+      ]
+    },
+    "ActionDismissAlias": {
+      "type": "object",
+      "properties": {
+        "tags": {
+          "type": "object",
+          "additionalProperties": {
+            "type": "integer"
+          }
+        },
+        "type": {
+          "type": "string",
+          "const": "dismiss-alias"
+        }
+      },
+      "required": [
+        "type"
+      ]
+    },
+    "ActionGroup": {
+      "type": "object",
+      "properties": {
+        "actions": {
+          "type": "array",
+          "items": {
+            "$ref": "#/components/schemas/Action"
+          }
+        },
+        "primary": {
+          "$ref": "#/components/schemas/Action"
+        },
+        "type": {
+          "type": "string",
+          "const": "group"
+        }
+      },
+      "required": [
+        "type",
+        "actions"
+      ]
+    },
+    "Other": {
+      "oneOf": [
+        {
+          "$ref": "#/components/schemas/OtherDismiss"
+        },
+        {
+          "$ref": "#/components/schemas/ActionDismissAlias"
+        }
+      ],
+      "discriminator": {
+        "propertyName": "type",
+        "mapping": {
+          "dismiss": "#/components/schemas/OtherDismiss",
+          "dismiss-alias": "#/components/schemas/ActionDismissAlias"
+        }
+      }
+    },
+    "OtherDismiss": {
+      "type": "object",
+      "properties": {
+        "reason": {
+          "type": "string"
+        },
+        "type": {
+          "type": "string",
+          "const": "dismiss"
+        }
+      },
+      "required": [
+        "type",
+        "reason"
+      ]
+    }
+  }
+}`
+
+	if string(b) != expected {
+		t.Fatalf("expected %s, got %s", expected, b)
+	}
+}
+
+func TestGenerator_names(t *testing.T) {
+	t.Run("nested poly", func(t *testing.T) {
+		g := openapi.NewGenerator()
+
+		if err := g.Add("Group", poly.Poly[IsAction, poly.Types1[ActionGroup]]{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, ok := g.Components().Schemas["IsAction"]; !ok {
+			t.Fatalf("expected nested Poly to be named after its interface, got %v", g.Components().Schemas)
+		}
+	})
+
+	t.Run("taken variant name", func(t *testing.T) {
+		g := openapi.NewGenerator()
+
+		if err := g.Add("ActionDismiss", Action{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, ok := g.Components().Schemas["ActionDismissDismiss"]; !ok {
+			t.Fatalf("expected variant to be named after its TypeName, got %v", g.Components().Schemas)
+		}
+	})
+
+	t.Run("taken poly name", func(t *testing.T) {
+		g := openapi.NewGenerator()
+
+		if err := g.Add("Action", Action{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err := g.Add("Action", Other{})
+		if err == nil || !strings.Contains(err.Error(), "component Action already exists") {
+			t.Fatalf("expected already exists error, got %v", err)
+		}
+	})
+
+	t.Run("renamed poly", func(t *testing.T) {
+		g := openapi.NewGenerator()
+
+		if err := g.Add("Action", Action{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if err := g.Add("Action", Action{}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		err := g.Add("Action2", Action{})
+		if err == nil || !strings.Contains(err.Error(), "is already added as Action") {
+			t.Fatalf("expected already added error, got %v", err)
+		}
+	})
+}
+
+type ActionReload struct {
+	Delay int `json:"delay"`
+}
+
+func (ActionReload) IsAction() {}
+
+func (ActionReload) TypeName() string {
+	return "reload"
+}
+
+func (ActionReload) PolyVersion() int {
+	return 2
+}
+
+func TestGenerator_versioned(t *testing.T) {
+	g := openapi.NewGenerator()
+
+	if err := g.Add("Reload", poly.Poly[IsAction, poly.Types1[ActionReload]]{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := json.Marshal(g.Components().Schemas["ActionReload"])
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	expected := `{"type":"object","properties":{"delay":{"type":"integer"},"type":{"type":"string","const":"reload"},"version":{"type":"integer","const":2}},"required":["type","version","delay"]}`
+	if string(b) != expected {
+		t.Fatalf("expected %s, got %s", expected, b)
+	}
+}
+
+type ActionOpen struct {
+	URL string `json:"url"`
+}
+
+func (*ActionOpen) IsAction() {}
+
+func (*ActionOpen) TypeName() string {
+	return "open"
+}
+
+func (ActionOpen) PolyVersion() int {
+	return 3
+}
+
+func TestGenerator_versionedPointer(t *testing.T) {
+	g := openapi.NewGenerator()
+
+	if err := g.Add("Open", poly.Poly[IsAction, poly.Types1[*ActionOpen]]{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := json.Marshal(g.Components().Schemas["ActionOpen"])
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	if expected := `"version":{"type":"integer","const":3}`; !strings.Contains(string(b), expected) {
+		t.Fatalf("expected %s in %s", expected, b)
+	}
+}
+
+func TestGenerator_errors(t *testing.T) {
+	t.Run("not poly", func(t *testing.T) {
+		err := openapi.NewGenerator().Add("Action", ActionDismiss{})
+		if err == nil || !strings.Contains(err.Error(), "openapi_test.ActionDismiss is not a poly.Poly") {
+			t.Fatalf("expected not poly error, got %v", err)
+		}
+	})
+
+	t.Run("duplicate TypeName", func(t *testing.T) {
+		err := openapi.NewGenerator().Add("Duplicated", Duplicated{})
+		if err == nil || !strings.Contains(err.Error(), "duplicate TypeName dismiss") {
+			t.Fatalf("expected duplicate TypeName error, got %v", err)
+		}
+	})
+}
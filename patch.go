@@ -0,0 +1,443 @@
+package poly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ApplyPatch applies the JSON patch (RFC 6902) to the value of the Poly.
+// The operations are applied to the JSON of the value including the discriminator,
+// so replacing "/type" changes the type of the value unless StrictPatch forbids it.
+// The value is replaced with a new one decoded from the patched JSON,
+// and it is left untouched if any of the operations fails.
+func ApplyPatch[I any, T Types](p *Poly[I, T], patch []byte) error {
+	doc, err := p.decodeAny()
+	if err != nil {
+		return err
+	}
+
+	patchDoc, err := decodeAny(patch)
+	if err != nil {
+		return fmt.Errorf("poly: cannot decode patch: %w", err)
+	}
+
+	ops, ok := patchDoc.([]any)
+	if !ok {
+		return errors.New("poly: patch must be a JSON array")
+	}
+
+	for i, op := range ops {
+		doc, err = applyOperation(doc, op)
+		if err != nil {
+			return fmt.Errorf("poly: cannot apply patch operation %d: %w", i, err)
+		}
+	}
+
+	return p.replaceWith(doc)
+}
+
+// CreatePatch returns the JSON patch (RFC 6902) that turns from into to when applied with ApplyPatch.
+// Objects are compared member by member, while other values, including arrays, are replaced as a whole.
+func CreatePatch[I any, T Types](from, to Poly[I, T]) ([]byte, error) {
+	fromDoc, err := from.decodeAny()
+	if err != nil {
+		return nil, err
+	}
+
+	toDoc, err := to.decodeAny()
+	if err != nil {
+		return nil, err
+	}
+
+	ops := createPatch([]map[string]any{}, "", fromDoc, toDoc)
+
+	patch, err := json.Marshal(ops)
+	if err != nil {
+		return nil, fmt.Errorf("poly: cannot encode patch: %w", err)
+	}
+
+	return patch, nil
+}
+
+func applyOperation(doc, op any) (any, error) {
+	fields, ok := op.(map[string]any)
+	if !ok {
+		return nil, errors.New("operation must be a JSON object")
+	}
+
+	name, _ := fields["op"].(string)
+
+	path, ok := fields["path"].(string)
+	if !ok {
+		return nil, errors.New("missing 'path'")
+	}
+
+	value, hasValue := fields["value"]
+	if !hasValue && (name == "add" || name == "replace" || name == "test") {
+		return nil, errors.New("missing 'value'")
+	}
+
+	from, hasFrom := fields["from"].(string)
+	if !hasFrom && (name == "move" || name == "copy") {
+		return nil, errors.New("missing 'from'")
+	}
+
+	switch name {
+	case "add":
+		return addValue(doc, path, value)
+	case "remove":
+		doc, _, err := removeValue(doc, path)
+
+		return doc, err
+	case "replace":
+		doc, _, err := removeValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		return addValue(doc, path, value)
+	case "move":
+		if strings.HasPrefix(path, from+"/") {
+			return nil, fmt.Errorf("cannot move %s to its child %s", from, path)
+		}
+
+		doc, value, err := removeValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		return addValue(doc, path, value)
+	case "copy":
+		value, err := getValue(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		return addValue(doc, path, deepCopy(value))
+	case "test":
+		actual, err := getValue(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !equalJSON(actual, value) {
+			return nil, fmt.Errorf("test failed for %s", path)
+		}
+
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown op %q", name)
+	}
+}
+
+// parsePointer splits the JSON pointer (RFC 6901) into unescaped reference tokens.
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", path)
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func arrayIndex(token string, length int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i >= length || token != strconv.Itoa(i) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	return i, nil
+}
+
+func getValue(doc any, path string) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range tokens {
+		switch container := doc.(type) {
+		case map[string]any:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", path)
+			}
+
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(container))
+			if err != nil {
+				return nil, err
+			}
+
+			doc = container[i]
+		default:
+			return nil, fmt.Errorf("path %s does not exist", path)
+		}
+	}
+
+	return doc, nil
+}
+
+// addValue adds the value at the path and returns the updated document.
+func addValue(doc any, path string, value any) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPath := path[:strings.LastIndex(path, "/")]
+
+	parent, err := getValue(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	token := tokens[len(tokens)-1]
+
+	switch container := parent.(type) {
+	case map[string]any:
+		container[token] = value
+
+		return doc, nil
+	case []any:
+		i := len(container)
+		if token != "-" {
+			if i, err = arrayIndex(token, len(container)+1); err != nil {
+				return nil, err
+			}
+		}
+
+		array := make([]any, 0, len(container)+1)
+		array = append(array, container[:i]...)
+		array = append(array, value)
+		array = append(array, container[i:]...)
+
+		return setValue(doc, parentPath, array)
+	default:
+		return nil, fmt.Errorf("path %s does not exist", parentPath)
+	}
+}
+
+// setValue replaces the existing value at the path and returns the updated document.
+func setValue(doc any, path string, value any) (any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPath := path[:strings.LastIndex(path, "/")]
+
+	parent, err := getValue(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	token := tokens[len(tokens)-1]
+
+	switch container := parent.(type) {
+	case map[string]any:
+		container[token] = value
+	case []any:
+		i, err := arrayIndex(token, len(container))
+		if err != nil {
+			return nil, err
+		}
+
+		container[i] = value
+	default:
+		return nil, fmt.Errorf("path %s does not exist", parentPath)
+	}
+
+	return doc, nil
+}
+
+// removeValue removes the value at the path and returns the updated document along with the removed value.
+func removeValue(doc any, path string) (any, any, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	parentPath := path[:strings.LastIndex(path, "/")]
+
+	parent, err := getValue(doc, parentPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	token := tokens[len(tokens)-1]
+
+	switch container := parent.(type) {
+	case map[string]any:
+		value, ok := container[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %s does not exist", path)
+		}
+
+		delete(container, token)
+
+		return doc, value, nil
+	case []any:
+		i, err := arrayIndex(token, len(container))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		value := container[i]
+
+		array := make([]any, 0, len(container)-1)
+		array = append(array, container[:i]...)
+		array = append(array, container[i+1:]...)
+
+		doc, err = setValue(doc, parentPath, array)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return doc, value, nil
+	default:
+		return nil, nil, fmt.Errorf("path %s does not exist", path)
+	}
+}
+
+// equalJSON reports whether the generic JSON values are equal, comparing numbers by value (RFC 6902, section 4.6).
+func equalJSON(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+
+		x, okX := new(big.Rat).SetString(a.String())
+		y, okY := new(big.Rat).SetString(b.String())
+
+		return okX && okY && x.Cmp(y) == 0
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for name, value := range a {
+			other, ok := b[name]
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func deepCopy(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		obj := make(map[string]any, len(value))
+		for name, v := range value {
+			obj[name] = deepCopy(v)
+		}
+
+		return obj
+	case []any:
+		array := make([]any, len(value))
+		for i, v := range value {
+			array[i] = deepCopy(v)
+		}
+
+		return array
+	default:
+		return value
+	}
+}
+
+// createPatch appends the operations that turn from into to at the path.
+func createPatch(ops []map[string]any, path string, from, to any) []map[string]any {
+	if reflect.DeepEqual(from, to) {
+		return ops
+	}
+
+	fromObj, fromOK := from.(map[string]any)
+	toObj, toOK := to.(map[string]any)
+
+	if !fromOK || !toOK {
+		return append(ops, map[string]any{"op": "replace", "path": path, "value": to})
+	}
+
+	names := make([]string, 0, len(fromObj)+len(toObj))
+
+	for name := range fromObj {
+		names = append(names, name)
+	}
+
+	for name := range toObj {
+		if _, ok := fromObj[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fromValue, inFrom := fromObj[name]
+		toValue, inTo := toObj[name]
+		namePath := path + "/" + escapePointer(name)
+
+		switch {
+		case !inTo:
+			ops = append(ops, map[string]any{"op": "remove", "path": namePath})
+		case !inFrom:
+			ops = append(ops, map[string]any{"op": "add", "path": namePath, "value": toValue})
+		default:
+			ops = createPatch(ops, namePath, fromValue, toValue)
+		}
+	}
+
+	return ops
+}
@@ -0,0 +1,180 @@
+package poly_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    IsItemValue
+		patch    string
+		expected IsItemValue
+	}{
+		{
+			name:     "add",
+			value:    ItemMap{Labels: map[string]string{"a": "1"}},
+			patch:    `[{"op":"add","path":"/labels/b~1c","value":"2"},{"op":"add","path":"/key","value":"k"}]`,
+			expected: ItemMap{Key: "k", Labels: map[string]string{"a": "1", "b/c": "2"}},
+		},
+		{
+			name:     "remove",
+			value:    ItemMap{Key: "k", Labels: map[string]string{"a": "1", "b": "2"}},
+			patch:    `[{"op":"remove","path":"/labels/a"},{"op":"remove","path":"/key"}]`,
+			expected: ItemMap{Labels: map[string]string{"b": "2"}},
+		},
+		{
+			name:     "replace",
+			value:    ItemMap{Key: "k", Count: 1},
+			patch:    `[{"op":"test","path":"/count","value":1},{"op":"replace","path":"/count","value":9007199254740993}]`,
+			expected: ItemMap{Key: "k", Count: 9007199254740993},
+		},
+		{
+			name:     "test numbers by value",
+			value:    ItemMap{Key: "k", Count: 100},
+			patch:    `[{"op":"test","path":"/count","value":1.0e2},{"op":"test","path":"","value":{"type":"item-map","key":"k","count":100.0}}]`,
+			expected: ItemMap{Key: "k", Count: 100},
+		},
+		{
+			name:     "replace type",
+			value:    ItemValue2{Key: "k", Key2: "k2"},
+			patch:    `[{"op":"replace","path":"/type","value":"item-map"},{"op":"add","path":"/labels","value":{"a":"1"}}]`,
+			expected: ItemMap{Key: "k", Labels: map[string]string{"a": "1"}},
+		},
+		{
+			name:     "replace root",
+			value:    ItemValue2{Key: "k"},
+			patch:    `[{"op":"replace","path":"","value":{"type":"item-value-1"}}]`,
+			expected: ItemValue1{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := ItemMapValue{Value: tt.value}
+
+			if err := poly.ApplyPatch(&item, []byte(tt.patch)); err != nil {
+				t.Fatalf("patch error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tt.expected, item.Value) {
+				t.Fatalf("expected %#v, got %#v", tt.expected, item.Value)
+			}
+		})
+	}
+
+	t.Run("move and copy", func(t *testing.T) {
+		item := ItemMapValue{Value: ItemMap{Key: "k", Labels: map[string]string{"a": "1"}}}
+
+		patch := `[{"op":"copy","from":"/labels/a","path":"/labels/b"},{"op":"move","from":"/labels/a","path":"/key"}]`
+
+		if err := poly.ApplyPatch(&item, []byte(patch)); err != nil {
+			t.Fatalf("patch error: %v", err)
+		}
+
+		if expected := (ItemMap{Key: "1", Labels: map[string]string{"b": "1"}}); !reflect.DeepEqual(expected, item.Value) {
+			t.Fatalf("expected %#v, got %#v", expected, item.Value)
+		}
+	})
+
+	errorTests := []struct {
+		name  string
+		patch string
+		err   string
+	}{
+		{name: "failed number test", patch: `[{"op":"add","path":"/key2","value":1},{"op":"test","path":"/key2","value":1.5}]`, err: "test failed for /key2"},
+		{name: "failed test", patch: `[{"op":"remove","path":"/key"},{"op":"test","path":"/key2","value":"x"}]`, err: "test failed for /key2"},
+		{name: "missing path", patch: `[{"op":"remove","path":"/labels"}]`, err: "path /labels does not exist"},
+		{name: "unknown op", patch: `[{"op":"merge","path":"/key"}]`, err: `unknown op "merge"`},
+		{name: "missing value", patch: `[{"op":"add","path":"/key"}]`, err: "missing 'value'"},
+		{name: "not array", patch: `{"op":"add"}`, err: "poly: patch must be a JSON array"},
+		{name: "unknown type", patch: `[{"op":"replace","path":"/type","value":"item-value-unknown"}]`, err: "poly: unknown TypeName item-value-unknown to unmarshal"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := ItemMapValue{Value: ItemValue2{Key: "k", Key2: "k2"}}
+
+			err := poly.ApplyPatch(&item, []byte(tt.patch))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+
+			if item.Value != (ItemValue2{Key: "k", Key2: "k2"}) {
+				t.Fatalf("expected untouched value, got %#v", item.Value)
+			}
+		})
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		item := poly.Poly[IsItemValue, poly.StrictPatch[poly.Types2[ItemValue1, ItemValue2]]]{Value: ItemValue2{}}
+
+		var typeChangeErr *poly.TypeChangeError
+		if err := poly.ApplyPatch(&item, []byte(`[{"op":"replace","path":"/type","value":"item-value-1"}]`)); !errors.As(err, &typeChangeErr) {
+			t.Fatalf("expected TypeChangeError, got %v", err)
+		}
+	})
+}
+
+func TestCreatePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     IsItemValue
+		to       IsItemValue
+		expected string
+	}{
+		{
+			name:     "equal",
+			from:     ItemMap{Key: "k"},
+			to:       ItemMap{Key: "k"},
+			expected: `[]`,
+		},
+		{
+			name: "change",
+			from: ItemMap{Key: "k", Labels: map[string]string{"a": "1", "b": "2"}},
+			to:   ItemMap{Labels: map[string]string{"a": "1", "b": "3", "c/d": "4"}, Count: 1},
+			expected: `[{"op":"add","path":"/count","value":1},{"op":"remove","path":"/key"},` +
+				`{"op":"replace","path":"/labels/b","value":"3"},{"op":"add","path":"/labels/c~1d","value":"4"}]`,
+		},
+		{
+			name:     "type change",
+			from:     ItemValue2{Key: "k", Key2: "k2"},
+			to:       ItemMap{Key: "k"},
+			expected: `[{"op":"remove","path":"/key2"},{"op":"replace","path":"/type","value":"item-map"}]`,
+		},
+		{
+			name:     "to null",
+			from:     ItemValue2{Key: "k"},
+			to:       nil,
+			expected: `[{"op":"replace","path":"","value":null}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := ItemMapValue{Value: tt.from}, ItemMapValue{Value: tt.to}
+
+			patch, err := poly.CreatePatch(from, to)
+			if err != nil {
+				t.Fatalf("create patch error: %v", err)
+			}
+
+			if string(patch) != tt.expected {
+				t.Fatalf("expected %s, got %s", tt.expected, patch)
+			}
+
+			if err := poly.ApplyPatch(&from, patch); err != nil {
+				t.Fatalf("patch error: %v", err)
+			}
+
+			if !reflect.DeepEqual(to.Value, from.Value) {
+				t.Fatalf("expected %#v, got %#v", to.Value, from.Value)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 )
 
 // TypeName is an interface that types must implement to provide their unique name for polymorphic serialization.
@@ -21,20 +22,110 @@ type Type struct {
 }
 
 // NewType creates a new Type instance for a given TypeName.
+// For a pointer type, TypeName is called on a pointer to a zero value, so it can be declared with a value receiver.
 func NewType[T TypeName]() Type {
 	var t T
 
+	reflectType := reflect.TypeOf((*T)(nil)).Elem()
+	if reflectType.Kind() == reflect.Pointer {
+		t = reflect.New(reflectType.Elem()).Interface().(T) //nolint:forcetypeassert
+	}
+
 	return Type{
 		Name:        t.TypeName(),
-		ReflectType: reflect.TypeOf(t),
+		ReflectType: reflectType,
 	}
 }
 
 // Types is an interface that provides a list of all registered polymorphic types.
+// The list must not change between the calls, as it is computed once per type and cached.
 type Types interface {
 	Types() []Type
 }
 
+// StrictPatch wraps a list of types to forbid changing the type of an existing value when unmarshaling.
+// With it, a Poly holding a value only accepts the discriminator of that value (or no discriminator to patch it),
+// and a different one results in a *TypeChangeError leaving the value untouched:
+//
+//	type Action = poly.Poly[IsAction, poly.StrictPatch[poly.Types2[ActionDismiss, ActionDeepLink]]]
+type StrictPatch[T Types] struct {
+	types T
+}
+
+func (s StrictPatch[T]) Types() []Type {
+	return s.types.Types()
+}
+
+func (StrictPatch[T]) strictPatch() {}
+
+func (s StrictPatch[T]) unwrapTypes() Types {
+	return s.types
+}
+
+type strictPatcher interface {
+	strictPatch()
+}
+
+// typesWrapper is implemented by the wrappers of Types, e.g. StrictPatch, so the wrappers can be combined.
+type typesWrapper interface {
+	unwrapTypes() Types
+}
+
+// typesOption returns the first of T and the Types wrapped by it implementing X.
+func typesOption[X any, T Types]() (X, bool) {
+	var t Types = *new(T)
+
+	for {
+		if option, ok := t.(X); ok {
+			return option, true
+		}
+
+		wrapper, ok := t.(typesWrapper)
+		if !ok {
+			var zero X
+
+			return zero, false
+		}
+
+		t = wrapper.unwrapTypes()
+	}
+}
+
+// TypeChangeError is returned when unmarshaling would change the type of an existing value
+// of a Poly with StrictPatch types.
+type TypeChangeError struct {
+	From string // the TypeName of the existing value
+	To   string // the TypeName of the discriminator
+}
+
+func (e *TypeChangeError) Error() string {
+	return fmt.Sprintf("poly: cannot change TypeName %s to %s", e.From, e.To)
+}
+
+// checkTypeChange returns a *TypeChangeError if T forbids changing the type of an existing value.
+func checkTypeChange[T Types](from, to string) error {
+	if _, ok := typesOption[strictPatcher, T](); !ok || from == "" || from == to {
+		return nil
+	}
+
+	return &TypeChangeError{From: from, To: to}
+}
+
+// PolyValidator can be implemented by the types to validate their values.
+// PolyValidate is called after the value is unmarshaled by Poly, including patching,
+// and its error is returned from the unmarshaling.
+type PolyValidator interface {
+	PolyValidate() error
+}
+
+// BeforePolyMarshaler can be implemented by the types to prepare or check their values before marshaling.
+// BeforePolyMarshal is called before the value is marshaled by Poly in any encoding, see Encode,
+// and its error is returned from the marshaling.
+// As the value is not addressable, it should be implemented with a value receiver by non-pointer types.
+type BeforePolyMarshaler interface {
+	BeforePolyMarshal() error
+}
+
 // Poly is a generic struct that wraps an interface and handles polymorphic JSON marshaling and unmarshaling.
 // I is the interface type that the concrete types implement.
 // T is a type that implements the Types interface, providing the list of known concrete types.
@@ -42,44 +133,339 @@ type Poly[I any, T Types] struct {
 	Value I
 }
 
-// MarshalJSON implements the json.Marshaler interface for Poly.
-// It marshals the underlying value along with its TypeName as a discriminator.
-func (p Poly[I, T]) MarshalJSON() ([]byte, error) {
-	implData, err := json.Marshal(p.Value)
+// PolyTypes returns the types that can be held by the Poly, as listed by T.
+// It allows discovering the registered types of a Poly via reflection.
+func (Poly[I, T]) PolyTypes() []Type {
+	return append([]Type(nil), indexTypes[T]().types...)
+}
+
+// LookupType returns the Type listed by T with the given name.
+func LookupType[T Types](name string) (Type, bool) {
+	typ, ok := indexTypes[T]().byName[name]
+
+	return typ, ok
+}
+
+// typeIndex holds the types listed by a Types, computed once per Types.
+// err is set if different types have the same TypeName, and it is returned when decoding or encoding.
+type typeIndex struct {
+	types          []Type
+	byName         map[string]Type
+	versions       map[string]int    // TypeName -> version of the Versioned types
+	discriminators map[string]string // TypeName -> JSON members identifying the type, see jsonDiscriminator
+	err            error
+}
+
+// typeIndexes caches the index of every Types for the process, as the Types are only known as type parameters.
+var typeIndexes sync.Map //nolint:gochecknoglobals // (*T)(nil) of Types -> *typeIndex
+
+// indexTypes returns the cached index of the types listed by T.
+// It is keyed by a nil *T rather than its reflect.Type, so a lookup does not call into reflect.
+func indexTypes[T Types]() *typeIndex {
+	var key any = (*T)(nil)
+
+	if index, ok := typeIndexes.Load(key); ok {
+		return index.(*typeIndex) //nolint:forcetypeassert
+	}
+
+	var t T
+
+	index := &typeIndex{
+		types:          t.Types(),
+		byName:         make(map[string]Type),
+		versions:       make(map[string]int),
+		discriminators: make(map[string]string),
+	}
+
+	for _, typ := range index.types {
+		listed, ok := index.byName[typ.Name]
+		if !ok {
+			index.byName[typ.Name] = typ
+
+			version := typeVersion(typ)
+			if version > 0 {
+				index.versions[typ.Name] = version
+			}
+
+			index.discriminators[typ.Name] = jsonDiscriminator(typ.Name, version)
+
+			continue
+		}
+
+		if listed.ReflectType != typ.ReflectType && index.err == nil {
+			index.err = fmt.Errorf("poly: duplicate TypeName %s of %s and %s", typ.Name, listed.ReflectType, typ.ReflectType)
+		}
+	}
+
+	actual, _ := typeIndexes.LoadOrStore(key, index)
+
+	return actual.(*typeIndex) //nolint:forcetypeassert
+}
+
+// PeekType returns the Type listed by T for the discriminator 'type' of the JSON object in data.
+// Only the discriminator is decoded and the value itself is not created, so it is cheaper than
+// unmarshaling a Poly when only the type is needed, e.g. for routing messages.
+func PeekType[T Types](data []byte) (Type, error) {
+	var discriminator struct {
+		TypeName string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &discriminator); err != nil {
+		return Type{}, fmt.Errorf("poly: cannot unmarshal discriminator 'type': %w", err)
+	}
+
+	return lookupDiscriminator[T](discriminator.TypeName)
+}
+
+func lookupDiscriminator[T Types](typeName string) (Type, error) {
+	index := indexTypes[T]()
+	if index.err != nil {
+		return Type{}, index.err
+	}
+
+	if typeName == "" {
+		return Type{}, errors.New("poly: missing discriminator 'type'")
+	}
+
+	typ, ok := index.byName[typeName]
+	if !ok {
+		return Type{}, fmt.Errorf("poly: unknown TypeName %s to unmarshal", typeName)
+	}
+
+	return typ, nil
+}
+
+// ResolveType returns the Type listed by T for the current value of the Poly.
+// It returns false if there is no value to encode, i.e. the value is nil or a nil pointer.
+func (p Poly[I, T]) ResolveType() (Type, bool, error) {
+	if p.isNil() {
+		return Type{}, false, nil
+	}
+
+	typ, err := p.valueType()
 	if err != nil {
-		return nil, fmt.Errorf("poly: cannot marshal value %v: %w", p.Value, err)
+		return Type{}, false, err
 	}
 
-	if bytes.Equal(implData, []byte("null")) {
-		return implData, nil
+	return typ, true, nil
+}
+
+// isNil reports whether the value is nil or a nil pointer.
+func (p Poly[I, T]) isNil() bool {
+	if constructor, ok := constructorOf[T](); ok {
+		if _, nilPointer, ok := constructor.TypeNameOf(p.Value); ok {
+			return nilPointer
+		}
+	}
+
+	reflectValue := reflect.ValueOf(p.Value)
+
+	return !reflectValue.IsValid() || reflectValue.Kind() == reflect.Pointer && reflectValue.IsNil()
+}
+
+func (p Poly[I, T]) valueType() (Type, error) {
+	typeName, ok := "", false
+
+	if constructor, isConstructor := constructorOf[T](); isConstructor {
+		typeName, _, ok = constructor.TypeNameOf(p.Value)
 	}
 
-	tnValue, ok := reflect.ValueOf(p.Value).Interface().(TypeName)
 	if !ok {
-		return nil, fmt.Errorf("poly: cannot get TypeName of %T to marshal", p.Value)
+		tnValue, ok := reflect.ValueOf(p.Value).Interface().(TypeName)
+		if !ok {
+			return Type{}, fmt.Errorf("poly: cannot get TypeName of %T to marshal", p.Value)
+		}
+
+		typeName = tnValue.TypeName()
 	}
 
-	typeName := tnValue.TypeName()
+	index := indexTypes[T]()
+	if index.err != nil {
+		return Type{}, index.err
+	}
 
-	var (
-		t     T
-		found bool
-	)
+	typ, ok := index.byName[typeName]
+	if !ok {
+		return Type{}, fmt.Errorf("poly: unknown TypeName %s of %T to marshal", typeName, p.Value)
+	}
 
-	for _, typ := range t.Types() {
-		if typeName == typ.Name {
-			found = true
+	return typ, nil
+}
 
-			break
+// Decode sets the value of the Poly to the type listed by T with the given TypeName.
+// The value is decoded by decode, which receives a pointer to it.
+// If typeName is empty or matches the TypeName of the current value, the current value is patched.
+// The decoded value is then converted to a pointer or a non-pointer if T is wrapped in AsPointers, AsValues or AsDeclared.
+// It allows implementing other encodings with the same rules as the JSON one.
+func (p *Poly[I, T]) Decode(typeName string, decode func(ptr any) error) error {
+	normalization := normalizationOf[T]()
+
+	// the current value is patched in the declared form, except a pointer that can be patched in place
+	current := *p
+	if normalization != asIs && (normalization != asPointers || !isPointer(p.Value)) {
+		if typ, ok, err := p.ResolveType(); err == nil && ok {
+			if current.Value, err = normalize(asDeclared, typ, p.Value); err != nil {
+				return err
+			}
 		}
 	}
 
-	if !found {
-		return nil, fmt.Errorf("poly: unknown TypeName %s of %T to marshal", typeName, p.Value)
+	typ, err := current.decodeValue(typeName, decode)
+	if err != nil {
+		return err
+	}
+
+	value, err := normalize(normalization, typ, current.Value)
+	if err != nil {
+		return err
 	}
 
+	p.Value = value
+
+	return validate(typ.Name, p.Value)
+}
+
+// decodeValue is Decode without the normalization and validation of the decoded value.
+func (p *Poly[I, T]) decodeValue(typeName string, decode func(ptr any) error) (Type, error) {
+	if constructor, ok := constructorOf[T](); ok {
+		if currentTypeName, nilPointer, ok := constructor.TypeNameOf(p.Value); ok || any(p.Value) == nil {
+			return p.decodeConstructed(constructor, currentTypeName, nilPointer, typeName, decode)
+		}
+	}
+
+	var currentTypeName string
+
+	reflectValue := reflect.ValueOf(p.Value)
+
+	if reflectValue.IsValid() {
+		if tnValue, ok := reflectValue.Interface().(TypeName); ok {
+			currentTypeName = tnValue.TypeName()
+		} else {
+			return Type{}, fmt.Errorf("poly: cannot get TypeName of %T to unmarshal", p.Value)
+		}
+	}
+
+	if typeName == "" {
+		typeName = currentTypeName
+	}
+
+	typ, err := lookupDiscriminator[T](typeName)
+	if err != nil {
+		return Type{}, err
+	}
+
+	if reflectValue.Kind() != reflect.Pointer || !reflectValue.IsNil() {
+		if err := checkTypeChange[T](currentTypeName, typ.Name); err != nil {
+			return Type{}, err
+		}
+	}
+
+	// if there was no value yet or it's a new type, we create a new value
+	if currentTypeName != typ.Name {
+		value, err := decodeNew(typ, false, p.Value, decode)
+		if err != nil {
+			return Type{}, err
+		}
+
+		p.Value = value
+
+		return typ, nil
+	}
+
+	// if there is a non-nil pointer to a struct, we can use it directly
+	if reflectValue.Kind() == reflect.Pointer && !reflectValue.IsNil() {
+		if err := decode(p.Value); err != nil {
+			return Type{}, fmt.Errorf("poly: cannot unmarshal '%s': %w", typ.ReflectType, err)
+		}
+
+		return typ, nil
+	}
+
+	// otherwise we should create a pointer and copy the existing value there
+	value, err := decodeNew(typ, true, p.Value, decode)
+	if err != nil {
+		return Type{}, err
+	}
+
+	p.Value = value
+
+	return typ, nil
+}
+
+// Encode returns the Type listed by T for the current value of the Poly to encode, calling its BeforePolyMarshal hook first.
+// It returns false if there is no value to encode, i.e. the value is nil or a nil pointer.
+// It is the counterpart of Decode for the other encodings, so they run the same hooks as the JSON one.
+func (p Poly[I, T]) Encode() (Type, bool, error) {
+	typ, ok, err := p.ResolveType()
+	if err != nil || !ok {
+		return Type{}, false, err
+	}
+
+	if err := p.beforeMarshal(); err != nil {
+		return Type{}, false, err
+	}
+
+	return typ, true, nil
+}
+
+// beforeMarshal calls the BeforePolyMarshal hook of the value if it implements BeforePolyMarshaler.
+func (p Poly[I, T]) beforeMarshal() error {
+	hook, ok := any(p.Value).(BeforePolyMarshaler)
+	if !ok {
+		return nil
+	}
+
+	typ, ok, err := p.ResolveType()
+	if err != nil || !ok {
+		return err
+	}
+
+	if err := hook.BeforePolyMarshal(); err != nil {
+		return fmt.Errorf("poly: cannot marshal %s: %w", typ.Name, err)
+	}
+
+	return nil
+}
+
+// validate calls the PolyValidate hook of the value if it implements PolyValidator.
+func validate(typeName string, value any) error {
+	validator, ok := value.(PolyValidator)
+	if !ok {
+		return nil
+	}
+
+	if err := validator.PolyValidate(); err != nil {
+		return fmt.Errorf("poly: invalid %s: %w", typeName, err)
+	}
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for Poly.
+// It marshals the underlying value along with its TypeName as a discriminator.
+func (p Poly[I, T]) MarshalJSON() ([]byte, error) {
+	if err := p.beforeMarshal(); err != nil {
+		return nil, err
+	}
+
+	implData, err := json.Marshal(p.Value)
+	if err != nil {
+		return nil, fmt.Errorf("poly: cannot marshal value %v: %w", p.Value, err)
+	}
+
+	if bytes.Equal(implData, []byte("null")) {
+		return implData, nil
+	}
+
+	typ, err := p.valueType()
+	if err != nil {
+		return nil, err
+	}
+
+	members := indexTypes[T]().discriminators[typ.Name]
+
 	if bytes.Equal(implData, []byte("{}")) {
-		return []byte(fmt.Sprintf(`{"type":"%s"}`, typeName)), nil
+		return []byte("{" + members + "}"), nil
 	}
 
 	if len(implData) == 0 || implData[0] != '{' {
@@ -88,8 +474,8 @@ func (p Poly[I, T]) MarshalJSON() ([]byte, error) {
 
 	var buf bytes.Buffer
 
-	buf.Grow(len(`{"type":"",`) + len(typeName) + len(implData) - 1)
-	fmt.Fprintf(&buf, `{"type":"%s",`, typeName)
+	buf.Grow(len(members) + len(implData) + 1)
+	buf.WriteString("{" + members + ",")
 	buf.Write(implData[1:])
 
 	return buf.Bytes(), nil
@@ -106,81 +492,91 @@ func (p *Poly[I, T]) UnmarshalJSON(data []byte) error {
 		return nil
 	}
 
-	var typeName string
+	var discriminator struct {
+		TypeName string `json:"type"`
+	}
 
-	reflectValue := reflect.ValueOf(p.Value)
+	if err := json.Unmarshal(data, &discriminator); err != nil {
+		return fmt.Errorf("poly: cannot unmarshal discriminator 'type': %w", err)
+	}
 
-	if reflectValue.IsValid() {
-		if tnValue, ok := reflectValue.Interface().(TypeName); ok {
-			typeName = tnValue.TypeName()
-		} else {
-			return fmt.Errorf("poly: cannot get TypeName of %T to unmarshal", p.Value)
+	index := indexTypes[T]()
+
+	if typ, ok := index.byName[discriminator.TypeName]; ok && !p.patches(typ.Name) {
+		var err error
+
+		if data, err = migrate(typ, index.versions[typ.Name], data); err != nil {
+			return err
 		}
 	}
 
-	discriminator := struct {
-		TypeName string `json:"type"`
-	}{
-		TypeName: typeName,
+	return p.Decode(discriminator.TypeName, func(ptr any) error {
+		return json.Unmarshal(data, ptr)
+	})
+}
+
+// MarshalBody returns the TypeName of the value, its version (0 if the type is not Versioned)
+// and its JSON without the discriminator and the version.
+// It allows transporting the TypeName and the version out of band, e.g. in headers or metadata, see UnmarshalBody.
+// For no value it returns an empty TypeName and null.
+func (p Poly[I, T]) MarshalBody() (string, int, []byte, error) {
+	typ, ok, err := p.Encode()
+	if err != nil {
+		return "", 0, nil, err
 	}
 
-	if err := json.Unmarshal(data, &discriminator); err != nil {
-		return fmt.Errorf("poly: cannot unmarshal discriminator 'type': %w", err)
+	if !ok {
+		return "", 0, []byte("null"), nil
 	}
 
-	if discriminator.TypeName == "" {
-		return errors.New("poly: missing discriminator 'type'")
+	body, err := json.Marshal(p.Value)
+	if err != nil {
+		return "", 0, nil, fmt.Errorf("poly: cannot marshal value %v: %w", p.Value, err)
 	}
 
-	var t T
-	for _, typ := range t.Types() {
-		if typ.Name != discriminator.TypeName {
-			continue
-		}
+	return typ.Name, indexTypes[T]().versions[typ.Name], body, nil
+}
 
-		// if there was no value yet or it's a new type, we create a new value
-		if typeName != typ.Name {
-			value, err := unmarshalNew(data, typ, false, p.Value)
-			if err != nil {
-				return err
-			}
+// patches reports whether the current value is of the type with the TypeName, so decoding it patches the value,
+// which is then not migrated, see Versioned.
+func (p *Poly[I, T]) patches(typeName string) bool {
+	typ, ok, err := p.ResolveType()
 
-			p.Value = value
+	return err == nil && ok && typ.Name == typeName
+}
 
-			return nil
-		}
+// UnmarshalBody unmarshals the JSON body without the discriminator to the type with the given TypeName.
+// If the type is Versioned, the body is upgraded from the given version, where 0 is treated as version 1,
+// like the JSON without a version. Like with the discriminator, an empty typeName or the TypeName of the current value
+// patches the current value without migrations. An empty body is treated as an empty object.
+func (p *Poly[I, T]) UnmarshalBody(typeName string, version int, body []byte) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
 
-		// if there is a non-nil pointer to a struct, we can use it directly
-		if reflectValue.Kind() == reflect.Pointer && !reflectValue.IsNil() {
-			if err := json.Unmarshal(data, p.Value); err != nil {
-				return fmt.Errorf("poly: cannot unmarshal '%s': %w", typ.ReflectType, err)
-			}
+	index := indexTypes[T]()
 
-			return nil
-		}
+	if typ, ok := index.byName[typeName]; ok && !p.patches(typ.Name) {
+		var err error
 
-		// otherwise we should create a pointer and copy the existing value there
-		value, err := unmarshalNew(data, typ, true, p.Value)
-		if err != nil {
+		if body, err = migrateBody(typ, index.versions[typ.Name], version, body); err != nil {
 			return err
 		}
-
-		p.Value = value
-
-		return nil
 	}
 
-	return fmt.Errorf("poly: unknown TypeName %s to unmarshal", discriminator.TypeName)
+	return p.Decode(typeName, func(ptr any) error {
+		return json.Unmarshal(body, ptr)
+	})
 }
 
-func unmarshalNew[I any](data []byte, typ Type, useCurrent bool, current I) (I, error) {
+func decodeNew[I any](typ Type, useCurrent bool, current I, decode func(ptr any) error) (I, error) {
 	ptr := reflect.New(typ.ReflectType)
 
 	if useCurrent {
 		ptr.Elem().Set(reflect.ValueOf(current))
 	}
 
-	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+	if err := decode(ptr.Interface()); err != nil {
 		return current, fmt.Errorf("poly: cannot unmarshal '%s': %w", typ.ReflectType, err)
 	}
 
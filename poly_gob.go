@@ -0,0 +1,96 @@
+package poly
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// RegisterGob registers all the types listed by T in encoding/gob under their TypeNames,
+// so interface values holding them can be sent through gob (e.g. with net/rpc)
+// and the stream does not depend on the names of the Go types.
+// Like gob.RegisterName, it panics if a TypeName is already registered for another type.
+func RegisterGob[T Types]() {
+	var t T
+	for _, typ := range t.Types() {
+		gob.RegisterName(typ.Name, reflect.Zero(typ.ReflectType).Interface())
+	}
+}
+
+// GobEncode implements the gob.GobEncoder interface for Poly.
+// It encodes the TypeName of the underlying value followed by the value itself,
+// so the underlying types do not need to be registered in gob.
+func (p Poly[I, T]) GobEncode() ([]byte, error) {
+	typ, ok, err := p.Encode()
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	enc := gob.NewEncoder(&buf)
+
+	if err := enc.Encode(typ.Name); err != nil {
+		return nil, fmt.Errorf("poly: cannot encode discriminator: %w", err)
+	}
+
+	// gob cannot encode structs without exported fields, so only the TypeName is sent for them
+	if !hasGobFields(typ.ReflectType) {
+		return buf.Bytes(), nil
+	}
+
+	if err := enc.Encode(p.Value); err != nil {
+		return nil, fmt.Errorf("poly: cannot encode value %v: %w", p.Value, err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements the gob.GobDecoder interface for Poly.
+// It decodes the value based on the encoded TypeName to the correct concrete type.
+func (p *Poly[I, T]) GobDecode(data []byte) error {
+	if len(data) == 0 {
+		var zero I
+
+		p.Value = zero
+
+		return nil
+	}
+
+	dec := gob.NewDecoder(bytes.NewReader(data))
+
+	var typeName string
+
+	if err := dec.Decode(&typeName); err != nil {
+		return fmt.Errorf("poly: cannot decode discriminator: %w", err)
+	}
+
+	return p.Decode(typeName, func(ptr any) error {
+		if err := dec.Decode(ptr); err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		return nil
+	})
+}
+
+func hasGobFields(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return true
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,127 @@
+package poly_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+func TestPoly_Gob(t *testing.T) {
+	t.Run("value", func(t *testing.T) {
+		in := ItemValueInner{
+			ItemV: ItemValue{Value: ItemValue1{}},
+			ItemP: &ItemValue{Value: ItemValue2{Key: "k"}},
+		}
+
+		var buf bytes.Buffer
+
+		if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+			t.Fatalf("encoding error: %v", err)
+		}
+
+		var out ItemValueInner
+
+		if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if got, ok := out.ItemV.Value.(ItemValue1); !ok {
+			t.Fatalf("expected ItemValue1, got %T", got)
+		}
+
+		if got, ok := out.ItemP.Value.(ItemValue2); !ok || got.Key != "k" {
+			t.Fatalf("expected ItemValue2, got %#v", out.ItemP.Value)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		in := ItemPointer{Value: &ItemPointer2{Key: "k", Key2: "k2"}}
+
+		data, err := in.GobEncode()
+		if err != nil {
+			t.Fatalf("encoding error: %v", err)
+		}
+
+		if !bytes.Contains(data, []byte("item-pointer-2")) {
+			t.Fatalf("expected TypeName in %q", data)
+		}
+
+		existing := &ItemPointer2{Key2: "existing"}
+		out := ItemPointer{Value: existing}
+
+		if err := out.GobDecode(data); err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if out.Value != existing || existing.Key != "k" || existing.Key2 != "k2" {
+			t.Fatalf("expected patched ItemPointer2, got %#v", out.Value)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		data, err := ItemPointer{}.GobEncode()
+		if err != nil {
+			t.Fatalf("encoding error: %v", err)
+		}
+
+		out := ItemPointer{Value: &ItemPointer1{}}
+
+		if err := out.GobDecode(data); err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if out.Value != nil {
+			t.Fatalf("expected nil, got %#v", out.Value)
+		}
+	})
+
+	t.Run("unknown encode", func(t *testing.T) {
+		_, err := ItemValue{Value: ItemValueUnknown{}}.GobEncode()
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-value-unknown") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+
+	t.Run("unknown decode", func(t *testing.T) {
+		data, err := poly.Poly[IsItemValue, poly.Types1[ItemValueUnknown]]{Value: ItemValueUnknown{}}.GobEncode()
+		if err != nil {
+			t.Fatalf("encoding error: %v", err)
+		}
+
+		var out ItemValue
+
+		err = out.GobDecode(data)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-value-unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
+
+func TestRegisterGob(t *testing.T) {
+	poly.RegisterGob[poly.Types2[*ItemPointer1, *ItemPointer2]]()
+
+	in := []IsItemPointer{&ItemPointer1{}, &ItemPointer2{Key: "k"}}
+
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatalf("encoding error: %v", err)
+	}
+
+	if !bytes.Contains(buf.Bytes(), []byte("item-pointer-2")) {
+		t.Fatalf("expected TypeName in %q", buf.Bytes())
+	}
+
+	var out []IsItemPointer
+
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("decoding error: %v", err)
+	}
+
+	if got, ok := out[1].(*ItemPointer2); !ok || got.Key != "k" {
+		t.Fatalf("expected *ItemPointer2, got %#v", out[1])
+	}
+}
@@ -8,12 +8,15 @@ import (
 	"encoding/json/v2"
 	"errors"
 	"fmt"
-	"reflect"
 )
 
 // MarshalJSONTo implements the json.MarshalerTo interface for Poly.
 // It marshals the underlying value along with its TypeName as a discriminator.
 func (p Poly[I, T]) MarshalJSONTo(enc *jsontext.Encoder) error {
+	if err := p.beforeMarshal(); err != nil {
+		return err
+	}
+
 	implData, err := json.Marshal(p.Value, enc.Options())
 	if err != nil {
 		return fmt.Errorf("poly: cannot marshal value %v: %w", p.Value, err)
@@ -23,32 +26,15 @@ func (p Poly[I, T]) MarshalJSONTo(enc *jsontext.Encoder) error {
 		return enc.WriteValue(implData)
 	}
 
-	tnValue, ok := reflect.ValueOf(p.Value).Interface().(TypeName)
-	if !ok {
-		return fmt.Errorf("poly: cannot get TypeName of %T to marshal", p.Value)
-	}
-
-	typeName := tnValue.TypeName()
-
-	var (
-		t     T
-		found bool
-	)
-
-	for _, typ := range t.Types() {
-		if typeName == typ.Name {
-			found = true
-
-			break
-		}
+	typ, err := p.valueType()
+	if err != nil {
+		return err
 	}
 
-	if !found {
-		return fmt.Errorf("poly: unknown TypeName %s of %T to marshal", typeName, p.Value)
-	}
+	members := indexTypes[T]().discriminators[typ.Name]
 
 	if bytes.Equal(implData, []byte("{}")) {
-		return enc.WriteValue([]byte(fmt.Sprintf(`{"type":"%s"}`, typeName))) //nolint:modernize
+		return enc.WriteValue([]byte("{" + members + "}"))
 	}
 
 	if len(implData) == 0 || implData[0] != '{' {
@@ -57,8 +43,8 @@ func (p Poly[I, T]) MarshalJSONTo(enc *jsontext.Encoder) error {
 
 	var buf bytes.Buffer
 
-	buf.Grow(len(`{"type":"",`) + len(typeName) + len(implData) - 1)
-	fmt.Fprintf(&buf, `{"type":"%s",`, typeName)
+	buf.Grow(len(members) + len(implData) + 1)
+	buf.WriteString("{" + members + ",")
 	buf.Write(implData[1:])
 
 	return enc.WriteValue(buf.Bytes())
@@ -67,104 +53,166 @@ func (p Poly[I, T]) MarshalJSONTo(enc *jsontext.Encoder) error {
 // UnmarshalJSONFrom implements the json.UnmarshalerFrom interface for Poly.
 // It unmarshals the JSON based on the 'type' discriminator field to the correct concrete type.
 func (p *Poly[I, T]) UnmarshalJSONFrom(dec *jsontext.Decoder) error {
-	var typeName string
+	if dec.PeekKind() == 'n' {
+		if _, err := dec.ReadToken(); err != nil {
+			return fmt.Errorf("poly: cannot unmarshal: %w", err)
+		}
 
-	reflectValue := reflect.ValueOf(p.Value)
+		var zero I
 
-	if reflectValue.IsValid() {
-		if tnValue, ok := reflectValue.Interface().(TypeName); ok {
-			typeName = tnValue.TypeName()
-		} else {
-			return fmt.Errorf("poly: cannot get TypeName of %T to unmarshal", p.Value)
-		}
-	}
+		p.Value = zero
 
-	fullData := &struct {
-		TypeName string         `json:"type"`
-		Data     jsontext.Value `json:",unknown"`
-	}{
-		TypeName: typeName,
-		Data:     []byte("{}"), // default to empty object to avoid unmarshaling into nil
+		return nil
 	}
 
-	if err := json.UnmarshalDecode(dec, &fullData, json.RejectUnknownMembers(false)); err != nil {
+	discriminator, data, err := readObject(dec)
+	if err != nil {
 		return fmt.Errorf("poly: cannot unmarshal: %w", err)
 	}
 
-	if fullData == nil {
-		var zero I
+	index := indexTypes[T]()
 
-		p.Value = zero
+	if typ, ok := index.byName[discriminator]; ok && !p.patches(typ.Name) {
+		if data, err = migrate(typ, index.versions[typ.Name], data); err != nil {
+			return err
+		}
+	}
 
-		return nil
+	return p.Decode(discriminator, func(ptr any) error {
+		return json.Unmarshal(data, ptr, dec.Options())
+	})
+}
+
+// PeekTypeFrom reads the next JSON object from dec and returns the Type listed by T for its discriminator 'type'
+// along with the object itself, so it can be unmarshaled later if needed.
+// Only the discriminator is decoded and the value itself is not created.
+// The returned object is only valid until the next call to dec, see jsontext.Decoder.ReadValue.
+func PeekTypeFrom[T Types](dec *jsontext.Decoder) (Type, jsontext.Value, error) {
+	value, err := dec.ReadValue()
+	if err != nil {
+		return Type{}, nil, fmt.Errorf("poly: cannot unmarshal discriminator 'type': %w", err)
 	}
 
-	if fullData.TypeName == "" {
-		return errors.New("poly: missing discriminator 'type'")
+	typeName, err := peekDiscriminator(value)
+	if err != nil {
+		return Type{}, nil, fmt.Errorf("poly: cannot unmarshal discriminator 'type': %w", err)
 	}
 
-	var t T
-	for _, typ := range t.Types() {
-		if typ.Name != fullData.TypeName {
-			continue
-		}
+	typ, err := lookupDiscriminator[T](typeName)
+	if err != nil {
+		return Type{}, nil, err
+	}
 
-		// if there was no value yet or it's a new type, we create a new value
-		if typeName != typ.Name {
-			value, err := unmarshalV2New(fullData.Data, typ, false, p.Value, dec.Options())
-			if err != nil {
-				return err
-			}
+	return typ, value, nil
+}
+
+// peekDiscriminator returns the value of the discriminator 'type' of the JSON object
+// skipping all the other members without decoding them.
+func peekDiscriminator(value jsontext.Value) (string, error) {
+	if value.Kind() != '{' {
+		return "", fmt.Errorf("expected JSON object, got %s", value)
+	}
 
-			p.Value = value
+	objDec := jsontext.NewDecoder(bytes.NewReader(value))
 
-			return nil
+	if _, err := objDec.ReadToken(); err != nil {
+		return "", err
+	}
+
+	for objDec.PeekKind() != '}' {
+		nameToken, err := objDec.ReadToken()
+		if err != nil {
+			return "", err
 		}
 
-		// if there is a non-nil pointer to a struct, we can use it directly
-		if reflectValue.Kind() == reflect.Pointer && !reflectValue.IsNil() {
-			if err := json.Unmarshal(fullData.Data, p.Value, dec.Options()); err != nil {
-				return fmt.Errorf("poly: cannot unmarshal '%s': %w", typ.ReflectType, err)
+		if nameToken.String() != "type" {
+			if err := objDec.SkipValue(); err != nil {
+				return "", err
 			}
 
-			return nil
+			continue
 		}
 
-		// otherwise we should create a pointer and copy the existing value there
-		value, err := unmarshalV2New(fullData.Data, typ, true, p.Value, dec.Options())
-		if err != nil {
-			return err
+		if objDec.PeekKind() != '"' {
+			return "", errors.New("discriminator 'type' must be a string")
 		}
 
-		p.Value = value
+		typeToken, err := objDec.ReadToken()
+		if err != nil {
+			return "", err
+		}
 
-		return nil
+		return typeToken.String(), nil
 	}
 
-	return fmt.Errorf("poly: unknown TypeName %s to unmarshal", fullData.TypeName)
+	return "", nil
 }
 
-func unmarshalV2New[I any](
-	data []byte,
-	typ Type,
-	useCurrent bool,
-	current I,
-	opts json.Options,
-) (I, error) {
-	ptr := reflect.New(typ.ReflectType)
+// readObject reads a JSON object from dec and splits it into the value of the
+// discriminator 'type' and an object with all the other members.
+func readObject(dec *jsontext.Decoder) (string, jsontext.Value, error) {
+	value, err := dec.ReadValue()
+	if err != nil {
+		return "", nil, err
+	}
+
+	if value.Kind() != '{' {
+		return "", nil, fmt.Errorf("expected JSON object, got %s", value)
+	}
+
+	var (
+		typeName string
+		buf      bytes.Buffer
+	)
+
+	objDec := jsontext.NewDecoder(bytes.NewReader(value))
+	objEnc := jsontext.NewEncoder(&buf)
+
+	if _, err := objDec.ReadToken(); err != nil {
+		return "", nil, err
+	}
 
-	if useCurrent {
-		ptr.Elem().Set(reflect.ValueOf(current))
+	if err := objEnc.WriteToken(jsontext.BeginObject); err != nil {
+		return "", nil, err
 	}
 
-	if err := json.Unmarshal(data, ptr.Interface(), opts); err != nil {
-		return current, fmt.Errorf("poly: cannot unmarshal '%s': %w", typ.ReflectType, err)
+	for objDec.PeekKind() != '}' {
+		nameToken, err := objDec.ReadToken()
+		if err != nil {
+			return "", nil, err
+		}
+
+		name := nameToken.String()
+
+		member, err := objDec.ReadValue()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if name == "type" {
+			if member.Kind() != '"' {
+				return "", nil, fmt.Errorf("discriminator 'type' must be a string, got %s", member)
+			}
+
+			if err := json.Unmarshal(member, &typeName); err != nil {
+				return "", nil, err
+			}
+
+			continue
+		}
+
+		if err := objEnc.WriteToken(jsontext.String(name)); err != nil {
+			return "", nil, err
+		}
+
+		if err := objEnc.WriteValue(member); err != nil {
+			return "", nil, err
+		}
 	}
 
-	value, ok := ptr.Elem().Interface().(I)
-	if !ok {
-		return current, fmt.Errorf("poly: cannot use '%v' as I", ptr.Interface())
+	if err := objEnc.WriteToken(jsontext.EndObject); err != nil {
+		return "", nil, err
 	}
 
-	return value, nil
+	return typeName, bytes.TrimSpace(buf.Bytes()), nil
 }
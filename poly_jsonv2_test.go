@@ -5,7 +5,13 @@ package poly_test
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/json/jsontext"
+	"io"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/ykalchevskiy/poly"
 )
 
 func TestPoly_JSONOptionsAreReusedInV2(t *testing.T) {
@@ -30,3 +36,44 @@ func TestPoly_JSONOptionsAreReusedInV2(t *testing.T) {
 		}
 	})
 }
+
+func TestPeekTypeFrom(t *testing.T) {
+	dec := jsontext.NewDecoder(strings.NewReader(`{"nested":{"type":"x"},"type":"item-value-2","key":"k"} {"type":"item-value-1"}`))
+
+	for _, expected := range []reflect.Type{reflect.TypeOf(ItemValue2{}), reflect.TypeOf(ItemValue1{})} {
+		typ, value, err := poly.PeekTypeFrom[poly.Types2[ItemValue1, ItemValue2]](dec)
+		if err != nil || typ.ReflectType != expected {
+			t.Fatalf("expected %s, got %v, %v", expected, typ, err)
+		}
+
+		var item ItemValue
+		if err := json.Unmarshal(value, &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if reflect.TypeOf(item.Value) != expected {
+			t.Fatalf("expected %s, got %T", expected, item.Value)
+		}
+	}
+
+	if _, _, err := poly.PeekTypeFrom[poly.Types2[ItemValue1, ItemValue2]](dec); err == nil || !strings.Contains(err.Error(), io.EOF.Error()) {
+		t.Fatalf("expected EOF error, got %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		data string
+		err  string
+	}{
+		"missing": {data: `{"key":"k"}`, err: "poly: missing discriminator 'type'"},
+		"unknown": {data: `{"type":"item-value-unknown"}`, err: "poly: unknown TypeName item-value-unknown to unmarshal"},
+		"invalid": {data: `{"type":1}`, err: "discriminator 'type' must be a string"},
+		"array":   {data: `[]`, err: "expected JSON object"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := poly.PeekTypeFrom[poly.Types2[ItemValue1, ItemValue2]](jsontext.NewDecoder(strings.NewReader(tc.data)))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
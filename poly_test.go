@@ -3,6 +3,7 @@ package poly_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
@@ -601,3 +602,288 @@ func TestPoly_ItemPointer(t *testing.T) {
 		}
 	})
 }
+
+func TestLookupType(t *testing.T) {
+	typ, ok := poly.LookupType[poly.Types2[ItemValue1, ItemValue2]]("item-value-2")
+	if !ok || typ.ReflectType != reflect.TypeOf(ItemValue2{}) {
+		t.Fatalf("expected ItemValue2, got %v", typ)
+	}
+
+	if _, ok := poly.LookupType[poly.Types2[ItemValue1, ItemValue2]]("item-value-unknown"); ok {
+		t.Fatalf("expected unknown TypeName not to be found")
+	}
+}
+
+func TestPoly_ResolveType(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		for _, item := range []ItemPointer{{}, {Value: (*ItemPointer1)(nil)}} {
+			if _, ok, err := item.ResolveType(); ok || err != nil {
+				t.Fatalf("expected no type, got %v, %v", ok, err)
+			}
+		}
+	})
+
+	t.Run("known", func(t *testing.T) {
+		typ, ok, err := ItemPointer{Value: &ItemPointer2{}}.ResolveType()
+		if !ok || err != nil || typ.Name != "item-pointer-2" {
+			t.Fatalf("expected item-pointer-2, got %v, %v, %v", typ, ok, err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		_, _, err := ItemPointer{Value: &ItemPointerUnknown{}}.ResolveType()
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-pointer-unknown") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
+
+func TestPeekType(t *testing.T) {
+	t.Run("known", func(t *testing.T) {
+		typ, err := poly.PeekType[poly.Types2[ItemValue1, ItemValue2]]([]byte(`{"key":"k","type":"item-value-2"}`))
+		if err != nil || typ.ReflectType != reflect.TypeOf(ItemValue2{}) {
+			t.Fatalf("expected ItemValue2, got %v, %v", typ, err)
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		data string
+		err  string
+	}{
+		"missing": {data: `{"key":"k"}`, err: "poly: missing discriminator 'type'"},
+		"unknown": {data: `{"type":"item-value-unknown"}`, err: "poly: unknown TypeName item-value-unknown to unmarshal"},
+		"invalid": {data: `{"type":1}`, err: "poly: cannot unmarshal discriminator 'type'"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := poly.PeekType[poly.Types2[ItemValue1, ItemValue2]]([]byte(tc.data))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+type HookValue struct {
+	Key string `json:"key"`
+}
+
+func (HookValue) IsItemValue() {}
+
+func (HookValue) TypeName() string {
+	return "hook-value"
+}
+
+func (v HookValue) PolyValidate() error {
+	if v.Key == "" {
+		return errors.New("key is required")
+	}
+
+	return nil
+}
+
+func (v HookValue) BeforePolyMarshal() error {
+	if v.Key == "" {
+		return errors.New("key is required")
+	}
+
+	return nil
+}
+
+type HookPointer struct {
+	Key     string `json:"key"`
+	Version int    `json:"version"`
+}
+
+func (*HookPointer) IsItemPointer() {}
+
+func (*HookPointer) TypeName() string {
+	return "hook-pointer"
+}
+
+func (p *HookPointer) BeforePolyMarshal() error {
+	p.Version++
+
+	return nil
+}
+
+func TestPoly_Hooks(t *testing.T) {
+	type Hook = poly.Poly[IsItemValue, poly.Types2[ItemValue1, HookValue]]
+
+	t.Run("validate", func(t *testing.T) {
+		var item Hook
+
+		if err := json.Unmarshal([]byte(`{"type":"hook-value","key":"k"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		item = Hook{}
+
+		err := json.Unmarshal([]byte(`{"type":"hook-value"}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: invalid hook-value: key is required") {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+	})
+
+	t.Run("validate patch", func(t *testing.T) {
+		item := Hook{Value: HookValue{Key: "k"}}
+
+		err := json.Unmarshal([]byte(`{"key":""}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: invalid hook-value: key is required") {
+			t.Fatalf("expected validation error, got %v", err)
+		}
+	})
+
+	t.Run("before marshal", func(t *testing.T) {
+		if _, err := json.Marshal(Hook{Value: HookValue{Key: "k"}}); err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		_, err := json.Marshal(Hook{Value: HookValue{}})
+		if err == nil || !strings.Contains(err.Error(), "poly: cannot marshal hook-value: key is required") {
+			t.Fatalf("expected hook error, got %v", err)
+		}
+	})
+
+	t.Run("before marshal pointer", func(t *testing.T) {
+		item := poly.Poly[IsItemPointer, poly.Types2[*ItemPointer1, *HookPointer]]{Value: &HookPointer{Key: "k"}}
+
+		bOut, err := json.Marshal(item)
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if expected := []byte(`{"type":"hook-pointer","key":"k","version":1}`); !bytes.Equal(expected, bOut) {
+			t.Fatalf("expected %s, got %s", expected, bOut)
+		}
+	})
+
+	t.Run("nil pointer", func(t *testing.T) {
+		item := poly.Poly[IsItemPointer, poly.Types2[*ItemPointer1, *HookPointer]]{Value: (*HookPointer)(nil)}
+
+		bOut, err := json.Marshal(item)
+		if err != nil || !bytes.Equal([]byte("null"), bOut) {
+			t.Fatalf("expected null, got %s, %v", bOut, err)
+		}
+	})
+}
+
+func TestStrictPatch(t *testing.T) {
+	type Strict = poly.Poly[IsItemValue, poly.StrictPatch[poly.Types2[ItemValue1, ItemValue2]]]
+
+	t.Run("patch", func(t *testing.T) {
+		item := Strict{Value: ItemValue2{Key: "k"}}
+
+		for _, bIn := range []string{`{"key2":"k2"}`, `{"type":"item-value-2","key2":"k2"}`} {
+			if err := json.Unmarshal([]byte(bIn), &item); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+		}
+
+		if item.Value != (ItemValue2{Key: "k", Key2: "k2"}) {
+			t.Fatalf("expected patched ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("type change", func(t *testing.T) {
+		item := Strict{Value: ItemValue2{Key: "k"}}
+
+		err := json.Unmarshal([]byte(`{"type":"item-value-1"}`), &item)
+
+		var typeChangeErr *poly.TypeChangeError
+		if !errors.As(err, &typeChangeErr) || typeChangeErr.From != "item-value-2" || typeChangeErr.To != "item-value-1" {
+			t.Fatalf("expected TypeChangeError, got %v", err)
+		}
+
+		if item.Value != (ItemValue2{Key: "k"}) {
+			t.Fatalf("expected untouched ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("no value", func(t *testing.T) {
+		var item Strict
+
+		if err := json.Unmarshal([]byte(`{"type":"item-value-1"}`), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		pointerItem := poly.Poly[IsItemPointer, poly.StrictPatch[poly.Types2[*ItemPointer1, *ItemPointer2]]]{
+			Value: (*ItemPointer2)(nil),
+		}
+
+		if err := json.Unmarshal([]byte(`{"type":"item-pointer-1"}`), &pointerItem); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+	})
+
+	t.Run("lazy", func(t *testing.T) {
+		item := poly.NewLazy[IsItemValue, poly.StrictPatch[poly.Types2[ItemValue1, ItemValue2]]](ItemValue2{})
+
+		var typeChangeErr *poly.TypeChangeError
+		if err := json.Unmarshal([]byte(`{"type":"item-value-1"}`), &item); !errors.As(err, &typeChangeErr) {
+			t.Fatalf("expected TypeChangeError, got %v", err)
+		}
+	})
+}
+
+func TestPoly_Body(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		typeName, version, body, err := ItemValue{Value: ItemValue2{Key: "k"}}.MarshalBody()
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if typeName != "item-value-2" || version != 0 || string(body) != `{"key":"k"}` {
+			t.Fatalf("expected item-value-2, 0 and {\"key\":\"k\"}, got %s, %d and %s", typeName, version, body)
+		}
+
+		var item ItemValue
+
+		if err := item.UnmarshalBody(typeName, version, body); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if item.Value != (ItemValue2{Key: "k"}) {
+			t.Fatalf("expected ItemValue2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("null", func(t *testing.T) {
+		typeName, _, body, err := ItemPointer{Value: (*ItemPointer2)(nil)}.MarshalBody()
+		if err != nil || typeName != "" || string(body) != "null" {
+			t.Fatalf("expected null, got %s, %s, %v", typeName, body, err)
+		}
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		var item ItemPointer
+
+		if err := item.UnmarshalBody("item-pointer-1", 0, nil); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if _, ok := item.Value.(*ItemPointer1); !ok {
+			t.Fatalf("expected *ItemPointer1, got %#v", item.Value)
+		}
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		item := ItemPointer{Value: &ItemPointer2{Key: "k"}}
+
+		if err := item.UnmarshalBody("", 0, []byte(`{"key2":"k2"}`)); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if *item.Value.(*ItemPointer2) != (ItemPointer2{Key: "k", Key2: "k2"}) { //nolint:forcetypeassert
+			t.Fatalf("expected patched ItemPointer2, got %#v", item.Value)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		var item ItemValue
+
+		err := item.UnmarshalBody("item-value-unknown", 0, []byte(`{}`))
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName item-value-unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
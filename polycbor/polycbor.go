@@ -0,0 +1,192 @@
+// Package polycbor allows polymorphic encoding and decoding of CBOR with github.com/fxamacker/cbor/v2,
+// using the 'type' entry of the map as the discriminator.
+// A CBOR tag is not used instead, as it would need a tag number for every variant,
+// while the entry keeps the shape of the JSON encoding.
+package polycbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+const majorTypeMap = 5
+
+// Poly wraps poly.Poly and additionally implements the cbor.Marshaler and cbor.Unmarshaler interfaces.
+type Poly[I any, T poly.Types] struct {
+	poly.Poly[I, T]
+}
+
+// MarshalCBOR implements the cbor.Marshaler interface for Poly.
+// It marshals the underlying value as a map along with its TypeName as a discriminator entry.
+func (p Poly[I, T]) MarshalCBOR() ([]byte, error) {
+	typ, ok, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return cbor.Marshal(nil)
+	}
+
+	implData, err := cbor.Marshal(p.Value)
+	if err != nil {
+		return nil, fmt.Errorf("polycbor: cannot marshal value %v: %w", p.Value, err)
+	}
+
+	n, headLen, err := mapHead(implData)
+	if err != nil {
+		return nil, fmt.Errorf("polycbor: expected CBOR map for %T: %w", p.Value, err)
+	}
+
+	key, err := cbor.Marshal("type")
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := cbor.Marshal(typ.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(implData)+len(key)+len(value)+8)
+
+	if n < 0 {
+		data = append(data, implData[:headLen]...)
+	} else {
+		data = appendHead(data, majorTypeMap, uint64(n)+1)
+	}
+
+	data = append(data, key...)
+	data = append(data, value...)
+	data = append(data, implData[headLen:]...)
+
+	return data, nil
+}
+
+// UnmarshalCBOR implements the cbor.Unmarshaler interface for Poly.
+// It unmarshals the CBOR map based on the 'type' discriminator entry to the correct concrete type.
+func (p *Poly[I, T]) UnmarshalCBOR(data []byte) error {
+	if len(data) == 1 && (data[0] == 0xf6 || data[0] == 0xf7) { // null or undefined
+		var zero I
+
+		p.Value = zero
+
+		return nil
+	}
+
+	typeName, implData, err := discriminator(data)
+	if err != nil {
+		return fmt.Errorf("polycbor: cannot unmarshal discriminator 'type': %w", err)
+	}
+
+	return p.Decode(typeName, func(ptr any) error {
+		return cbor.Unmarshal(implData, ptr)
+	})
+}
+
+// discriminator returns the 'type' entry of the CBOR map in data and the map without it,
+// so that the value can be a map of another type of values.
+func discriminator(data []byte) (string, []byte, error) {
+	n, headLen, err := mapHead(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		typeName string
+		entries  []byte
+		implLen  uint64
+	)
+
+	rest := data[headLen:]
+
+	for i := 0; n < 0 || i < n; i++ {
+		if n < 0 && len(rest) > 0 && rest[0] == 0xff { // break of an indefinite-length map
+			break
+		}
+
+		var key, value cbor.RawMessage
+
+		if rest, err = cbor.UnmarshalFirst(rest, &key); err != nil {
+			return "", nil, err
+		}
+
+		if rest, err = cbor.UnmarshalFirst(rest, &value); err != nil {
+			return "", nil, err
+		}
+
+		var name string
+
+		if cbor.Unmarshal(key, &name) == nil && name == "type" {
+			if err := cbor.Unmarshal(value, &typeName); err != nil {
+				return "", nil, err
+			}
+
+			continue
+		}
+
+		entries = append(append(entries, key...), value...)
+		implLen++
+	}
+
+	return typeName, append(appendHead(nil, majorTypeMap, implLen), entries...), nil
+}
+
+// mapHead returns the number of entries of the CBOR map in data and the length of its head.
+// The number of entries is negative for an indefinite-length map.
+func mapHead(data []byte) (int, int, error) {
+	if len(data) == 0 {
+		return 0, 0, errors.New("empty data")
+	}
+
+	if majorType := data[0] >> 5; majorType != majorTypeMap {
+		return 0, 0, fmt.Errorf("got major type %d", majorType)
+	}
+
+	var n uint64
+
+	headLen := 1
+
+	switch info := data[0] & 0x1f; {
+	case info < 24:
+		n = uint64(info)
+	case info == 24 && len(data) >= 2:
+		n, headLen = uint64(data[1]), 2
+	case info == 25 && len(data) >= 3:
+		n, headLen = uint64(binary.BigEndian.Uint16(data[1:])), 3
+	case info == 26 && len(data) >= 5:
+		n, headLen = uint64(binary.BigEndian.Uint32(data[1:])), 5
+	case info == 27 && len(data) >= 9:
+		n, headLen = binary.BigEndian.Uint64(data[1:]), 9
+	case info == 31:
+		return -1, 1, nil
+	default:
+		return 0, 0, errors.New("malformed map head")
+	}
+
+	return int(n), headLen, nil
+}
+
+func appendHead(data []byte, majorType byte, n uint64) []byte {
+	var buf [8]byte
+
+	binary.BigEndian.PutUint64(buf[:], n)
+
+	switch {
+	case n < 24:
+		return append(data, majorType<<5|byte(n))
+	case n <= 0xff:
+		return append(data, majorType<<5|24, byte(n))
+	case n <= 0xffff:
+		return append(append(data, majorType<<5|25), buf[6:]...)
+	case n <= 0xffffffff:
+		return append(append(data, majorType<<5|26), buf[4:]...)
+	default:
+		return append(append(data, majorType<<5|27), buf[:]...)
+	}
+}
@@ -0,0 +1,278 @@
+package polycbor_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polycbor"
+)
+
+type IsAction interface {
+	IsAction()
+}
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) IsAction() {}
+
+func (ActionDismiss) TypeName() string {
+	return "dismiss"
+}
+
+type ActionDeepLink struct {
+	URL   string `cbor:"url"`
+	Title string `cbor:"title,omitempty"`
+}
+
+func (*ActionDeepLink) IsAction() {}
+
+func (*ActionDeepLink) TypeName() string {
+	return "deep-link"
+}
+
+type ActionUnknown struct{}
+
+func (ActionUnknown) IsAction() {}
+
+func (ActionUnknown) TypeName() string {
+	return "unknown"
+}
+
+type ActionTypes = poly.Types2[ActionDismiss, *ActionDeepLink]
+
+type Action = polycbor.Poly[IsAction, ActionTypes]
+
+type Notification struct {
+	Actions []Action `cbor:"actions"`
+	Primary *Action  `cbor:"primary"`
+}
+
+func TestPoly_CBOR(t *testing.T) {
+	in := Notification{
+		Actions: []Action{
+			{Poly: poly.Poly[IsAction, ActionTypes]{Value: ActionDismiss{}}},
+			{Poly: poly.Poly[IsAction, ActionTypes]{Value: &ActionDeepLink{URL: "url", Title: "title"}}},
+			{},
+		},
+	}
+
+	data, err := cbor.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	var out Notification
+
+	if err := cbor.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if got, ok := out.Actions[0].Value.(ActionDismiss); !ok {
+		t.Fatalf("expected ActionDismiss, got %T", got)
+	}
+
+	if got, ok := out.Actions[1].Value.(*ActionDeepLink); !ok || got.URL != "url" || got.Title != "title" {
+		t.Fatalf("expected *ActionDeepLink, got %#v", out.Actions[1].Value)
+	}
+
+	if out.Actions[2].Value != nil {
+		t.Fatalf("expected nil, got %#v", out.Actions[2].Value)
+	}
+
+	if out.Primary != nil {
+		t.Fatalf("expected nil, got %#v", out.Primary)
+	}
+
+	var entries []map[string]any
+
+	if err := cbor.Unmarshal(data, &struct {
+		Actions *[]map[string]any `cbor:"actions"`
+	}{Actions: &entries}); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if entries[0]["type"] != "dismiss" || entries[1]["type"] != "deep-link" || entries[1]["url"] != "url" {
+		t.Fatalf("expected discriminator entries, got %v", entries)
+	}
+}
+
+func TestPoly_CBOR_patch(t *testing.T) {
+	deepLink := &ActionDeepLink{URL: "url", Title: "title"}
+	action := Action{Poly: poly.Poly[IsAction, ActionTypes]{Value: deepLink}}
+
+	data, err := cbor.Marshal(map[string]string{"url": "url-2"})
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	if err := cbor.Unmarshal(data, &action); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if action.Value != deepLink || deepLink.URL != "url-2" || deepLink.Title != "title" {
+		t.Fatalf("expected patched *ActionDeepLink, got %#v", action.Value)
+	}
+}
+
+// ActionParams is a map variant to check the map heads of any size.
+type ActionParams map[string]int
+
+func (ActionParams) IsAction() {}
+
+func (ActionParams) TypeName() string {
+	return "params"
+}
+
+// ActionRetry is marshaled as an indefinite-length map.
+type ActionRetry struct {
+	Count int `cbor:"count"`
+}
+
+func (ActionRetry) IsAction() {}
+
+func (ActionRetry) TypeName() string {
+	return "retry"
+}
+
+func (v ActionRetry) MarshalCBOR() ([]byte, error) {
+	return []byte{0xbf, 0x65, 'c', 'o', 'u', 'n', 't', byte(v.Count), 0xff}, nil
+}
+
+func TestPoly_CBOR_mapHead(t *testing.T) {
+	type Action = polycbor.Poly[IsAction, poly.Types2[ActionParams, ActionRetry]]
+
+	for _, tc := range []struct {
+		entries int
+		head    []byte
+	}{
+		{entries: 0, head: []byte{0xa1}},
+		{entries: 22, head: []byte{0xb7}},
+		{entries: 23, head: []byte{0xb8, 24}},
+		{entries: 24, head: []byte{0xb8, 25}},
+		{entries: 255, head: []byte{0xb9, 0x01, 0x00}},
+		{entries: 256, head: []byte{0xb9, 0x01, 0x01}},
+		{entries: 65535, head: []byte{0xba, 0x00, 0x01, 0x00, 0x00}},
+		{entries: 65536, head: []byte{0xba, 0x00, 0x01, 0x00, 0x01}},
+	} {
+		t.Run(strconv.Itoa(tc.entries), func(t *testing.T) {
+			params := make(ActionParams, tc.entries)
+
+			for i := 0; i < tc.entries; i++ {
+				params[strconv.Itoa(i)] = i
+			}
+
+			data, err := cbor.Marshal(Action{Poly: poly.Poly[IsAction, poly.Types2[ActionParams, ActionRetry]]{Value: params}})
+			if err != nil {
+				t.Fatalf("marshaling error: %v", err)
+			}
+
+			if !bytes.HasPrefix(data, tc.head) {
+				t.Fatalf("expected head %x, got %x", tc.head, data[:len(tc.head)])
+			}
+
+			var action Action
+
+			if err := cbor.Unmarshal(data, &action); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+
+			if got, ok := action.Value.(ActionParams); !ok || len(got) != tc.entries || got["0"] != params["0"] {
+				t.Fatalf("expected ActionParams of %d entries, got %d", tc.entries, len(got))
+			}
+		})
+	}
+
+	t.Run("indefinite", func(t *testing.T) {
+		data, err := cbor.Marshal(Action{Poly: poly.Poly[IsAction, poly.Types2[ActionParams, ActionRetry]]{Value: ActionRetry{Count: 3}}})
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if data[0] != 0xbf || data[len(data)-1] != 0xff {
+			t.Fatalf("expected indefinite-length map, got %x", data)
+		}
+
+		var action Action
+
+		if err := cbor.Unmarshal(data, &action); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if action.Value != (ActionRetry{Count: 3}) {
+			t.Fatalf("expected ActionRetry, got %#v", action.Value)
+		}
+	})
+}
+
+func TestPoly_CBOR_errors(t *testing.T) {
+	t.Run("missing discriminator", func(t *testing.T) {
+		var action Action
+
+		data, err := cbor.Marshal(map[string]string{"url": "url"})
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		err = cbor.Unmarshal(data, &action)
+		if err == nil || !strings.Contains(err.Error(), "missing discriminator") {
+			t.Fatalf("expected missing discriminator error, got %v", err)
+		}
+	})
+
+	t.Run("unknown unmarshal", func(t *testing.T) {
+		var action Action
+
+		data, err := cbor.Marshal(map[string]string{"type": "unknown"})
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		err = cbor.Unmarshal(data, &action)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+
+	t.Run("unknown marshal", func(t *testing.T) {
+		_, err := cbor.Marshal(Action{Poly: poly.Poly[IsAction, ActionTypes]{Value: ActionUnknown{}}})
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
+
+// ActionChecked checks its URL before marshaling.
+type ActionChecked struct {
+	URL string `cbor:"url"`
+}
+
+func (ActionChecked) IsAction() {}
+
+func (ActionChecked) TypeName() string {
+	return "checked"
+}
+
+func (v ActionChecked) BeforePolyMarshal() error {
+	if v.URL == "" {
+		return errors.New("missing url")
+	}
+
+	return nil
+}
+
+func TestPoly_CBOR_beforeMarshal(t *testing.T) {
+	if _, err := cbor.Marshal(polycbor.Poly[IsAction, poly.Types1[ActionChecked]]{Poly: poly.Poly[IsAction, poly.Types1[ActionChecked]]{Value: ActionChecked{URL: "url"}}}); err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	_, err := cbor.Marshal(polycbor.Poly[IsAction, poly.Types1[ActionChecked]]{Poly: poly.Poly[IsAction, poly.Types1[ActionChecked]]{Value: ActionChecked{}}})
+	if err == nil || !strings.Contains(err.Error(), "poly: cannot marshal checked: missing url") {
+		t.Fatalf("expected BeforePolyMarshal error, got %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+// Package polycloudevents allows transporting poly.Poly values as CloudEvents (https://cloudevents.io).
+//
+// The 'type' attribute of the event is the TypeName of the value, and the 'data' of the event
+// is the JSON of the value without the discriminator. Both the structured content mode,
+// where the whole event is a JSON object, and the binary content mode, where the attributes
+// are transported in headers and the data is the body, are supported.
+package polycloudevents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// SpecVersion is the version of the CloudEvents specification implemented by the package.
+const SpecVersion = "1.0"
+
+// ContentType is the media type of the data of the events.
+const ContentType = "application/json"
+
+// StructuredContentType is the media type of the events in the structured content mode.
+const StructuredContentType = "application/cloudevents+json"
+
+// VersionExtension is the extension attribute holding the version of the data if its type is poly.Versioned,
+// so older events are migrated when they are read.
+const VersionExtension = "polyversion"
+
+// headerPrefix is the prefix of the headers holding the attributes in the binary content mode of the HTTP binding.
+const headerPrefix = "Ce-"
+
+// CloudEvent is a CloudEvent holding a poly.Poly value as its data.
+// The 'type' attribute is not stored separately: it is the TypeName of Data.
+type CloudEvent[I any, T poly.Types] struct {
+	ID         string
+	Source     string
+	Subject    string
+	Time       time.Time
+	DataSchema string
+	// Extensions are the extension attributes of the event.
+	// In the structured content mode, values which are not JSON strings are kept as their JSON text.
+	Extensions map[string]string
+	Data       poly.Poly[I, T]
+}
+
+// isAttribute reports whether the name is an attribute which is not an extension.
+func isAttribute(name string) bool {
+	switch name {
+	case VersionExtension, "specversion", "id", "source", "type", "subject", "time",
+		"dataschema", "datacontenttype", "data", "data_base64":
+		return true
+	default:
+		return false
+	}
+}
+
+// Type returns the 'type' attribute of the event, i.e. the TypeName of its data.
+func (e CloudEvent[I, T]) Type() (string, error) {
+	typ, ok, err := e.Data.ResolveType()
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		return "", errors.New("polycloudevents: missing data to get 'type'")
+	}
+
+	return typ.Name, nil
+}
+
+func (e CloudEvent[I, T]) marshalData() (string, int, []byte, error) {
+	typeName, version, data, err := e.Data.MarshalBody()
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	if typeName == "" {
+		return "", 0, nil, errors.New("polycloudevents: missing data to get 'type'")
+	}
+
+	return typeName, version, data, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface for CloudEvent.
+// It marshals the event in the structured content mode.
+func (e CloudEvent[I, T]) MarshalJSON() ([]byte, error) {
+	typeName, version, data, err := e.marshalData()
+	if err != nil {
+		return nil, err
+	}
+
+	members := map[string]any{
+		"specversion":     SpecVersion,
+		"id":              e.ID,
+		"source":          e.Source,
+		"type":            typeName,
+		"datacontenttype": ContentType,
+		"data":            json.RawMessage(data),
+	}
+
+	for name, value := range e.Extensions {
+		if isAttribute(name) {
+			return nil, fmt.Errorf("polycloudevents: extension %s conflicts with a context attribute", name)
+		}
+
+		members[name] = value
+	}
+
+	if e.Subject != "" {
+		members["subject"] = e.Subject
+	}
+
+	if !e.Time.IsZero() {
+		members["time"] = e.Time.Format(time.RFC3339Nano)
+	}
+
+	if e.DataSchema != "" {
+		members["dataschema"] = e.DataSchema
+	}
+
+	if version > 0 {
+		members[VersionExtension] = version
+	}
+
+	return json.Marshal(members)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface for CloudEvent.
+// It unmarshals the event in the structured content mode, decoding its data based on the 'type' attribute.
+func (e *CloudEvent[I, T]) UnmarshalJSON(b []byte) error {
+	var members map[string]json.RawMessage
+
+	if err := json.Unmarshal(b, &members); err != nil {
+		return fmt.Errorf("polycloudevents: cannot unmarshal event: %w", err)
+	}
+
+	attrs := make(map[string]string, len(members))
+
+	for name, value := range members {
+		if name == "data" || name == "data_base64" {
+			continue
+		}
+
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = string(value)
+		}
+
+		attrs[name] = s
+	}
+
+	if contentType := attrs["datacontenttype"]; !isJSON(contentType) {
+		return fmt.Errorf("polycloudevents: unsupported datacontenttype %s", contentType)
+	}
+
+	if _, ok := members["data_base64"]; ok {
+		return errors.New("polycloudevents: unsupported data_base64")
+	}
+
+	return e.set(attrs, members["data"])
+}
+
+// WriteBinary writes the attributes of the event to the headers in the binary content mode of the HTTP binding
+// and returns the data to be used as the body.
+func (e CloudEvent[I, T]) WriteBinary(header http.Header) ([]byte, error) {
+	typeName, version, data, err := e.marshalData()
+	if err != nil {
+		return nil, err
+	}
+
+	header.Set(headerPrefix+"Specversion", SpecVersion)
+	header.Set(headerPrefix+"Id", e.ID)
+	header.Set(headerPrefix+"Source", e.Source)
+	header.Set(headerPrefix+"Type", typeName)
+	header.Set("Content-Type", ContentType)
+
+	if e.Subject != "" {
+		header.Set(headerPrefix+"Subject", e.Subject)
+	}
+
+	if !e.Time.IsZero() {
+		header.Set(headerPrefix+"Time", e.Time.Format(time.RFC3339Nano))
+	}
+
+	if e.DataSchema != "" {
+		header.Set(headerPrefix+"Dataschema", e.DataSchema)
+	}
+
+	if version > 0 {
+		header.Set(headerPrefix+VersionExtension, strconv.Itoa(version))
+	}
+
+	names := make([]string, 0, len(e.Extensions))
+	for name := range e.Extensions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		if isAttribute(name) {
+			return nil, fmt.Errorf("polycloudevents: extension %s conflicts with a context attribute", name)
+		}
+
+		header.Set(headerPrefix+name, e.Extensions[name])
+	}
+
+	return data, nil
+}
+
+// ReadBinary reads the event in the binary content mode of the HTTP binding from the headers and the body,
+// decoding the body based on the 'Ce-Type' header.
+func (e *CloudEvent[I, T]) ReadBinary(header http.Header, body []byte) error {
+	if contentType := header.Get("Content-Type"); !isJSON(contentType) {
+		return fmt.Errorf("polycloudevents: unsupported Content-Type %s", contentType)
+	}
+
+	attrs := make(map[string]string, len(header))
+
+	for name, values := range header {
+		if len(values) == 0 || len(name) <= len(headerPrefix) || !strings.EqualFold(name[:len(headerPrefix)], headerPrefix) {
+			continue
+		}
+
+		attrs[strings.ToLower(name[len(headerPrefix):])] = values[0]
+	}
+
+	return e.set(attrs, body)
+}
+
+// set sets the event from its attributes and the JSON of its data.
+func (e *CloudEvent[I, T]) set(attrs map[string]string, data json.RawMessage) error {
+	if specVersion := attrs["specversion"]; specVersion != SpecVersion {
+		return fmt.Errorf("polycloudevents: unsupported specversion %q", specVersion)
+	}
+
+	for _, name := range []string{"id", "source", "type"} {
+		if attrs[name] == "" {
+			return fmt.Errorf("polycloudevents: missing required attribute %s", name)
+		}
+	}
+
+	event := CloudEvent[I, T]{
+		ID:         attrs["id"],
+		Source:     attrs["source"],
+		Subject:    attrs["subject"],
+		DataSchema: attrs["dataschema"],
+	}
+
+	if attrs["time"] != "" {
+		t, err := time.Parse(time.RFC3339Nano, attrs["time"])
+		if err != nil {
+			return fmt.Errorf("polycloudevents: cannot parse time: %w", err)
+		}
+
+		event.Time = t
+	}
+
+	var version int
+
+	if attrs[VersionExtension] != "" {
+		v, err := strconv.Atoi(attrs[VersionExtension])
+		if err != nil {
+			return fmt.Errorf("polycloudevents: cannot parse %s: %w", VersionExtension, err)
+		}
+
+		version = v
+	}
+
+	for name, value := range attrs {
+		if isAttribute(name) {
+			continue
+		}
+
+		if event.Extensions == nil {
+			event.Extensions = make(map[string]string)
+		}
+
+		event.Extensions[name] = value
+	}
+
+	if err := event.Data.UnmarshalBody(attrs["type"], version, data); err != nil {
+		return err
+	}
+
+	*e = event
+
+	return nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(strings.ToLower(mediaType))
+
+	return mediaType == "" || mediaType == ContentType || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,276 @@
+package polycloudevents_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polycloudevents"
+)
+
+type IsAction interface {
+	IsAction()
+}
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) IsAction() {}
+
+func (ActionDismiss) TypeName() string {
+	return "com.example.dismiss"
+}
+
+type ActionDeepLink struct {
+	URL string `json:"url"`
+}
+
+func (ActionDeepLink) IsAction() {}
+
+func (ActionDeepLink) TypeName() string {
+	return "com.example.deep-link"
+}
+
+type Event = polycloudevents.CloudEvent[IsAction, poly.Types2[ActionDismiss, ActionDeepLink]]
+
+func newEvent() Event {
+	return Event{
+		ID:         "1",
+		Source:     "/actions",
+		Subject:    "user-1",
+		Time:       time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extensions: map[string]string{"traceparent": "00-1-2-01"},
+		Data:       poly.Poly[IsAction, poly.Types2[ActionDismiss, ActionDeepLink]]{Value: ActionDeepLink{URL: "url"}},
+	}
+}
+
+func TestCloudEvent_Structured(t *testing.T) {
+	bOut, err := json.Marshal(newEvent())
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	expected := `{"data":{"url":"url"},"datacontenttype":"application/json","id":"1","source":"/actions",` +
+		`"specversion":"1.0","subject":"user-1","time":"2024-01-02T03:04:05Z","traceparent":"00-1-2-01",` +
+		`"type":"com.example.deep-link"}`
+	if string(bOut) != expected {
+		t.Fatalf("expected %s, got %s", expected, bOut)
+	}
+
+	var event Event
+
+	if err := json.Unmarshal(bOut, &event); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if !reflect.DeepEqual(newEvent(), event) {
+		t.Fatalf("expected %#v, got %#v", newEvent(), event)
+	}
+}
+
+func TestCloudEvent_Binary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body bytes.Buffer
+		if _, err := body.ReadFrom(r.Body); err != nil {
+			t.Errorf("reading error: %v", err)
+		}
+
+		var event Event
+		if err := event.ReadBinary(r.Header, body.Bytes()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		if !reflect.DeepEqual(newEvent(), event) {
+			t.Errorf("expected %#v, got %#v", newEvent(), event)
+		}
+	}))
+	defer server.Close()
+
+	header := http.Header{}
+
+	body, err := newEvent().WriteBinary(header)
+	if err != nil {
+		t.Fatalf("writing error: %v", err)
+	}
+
+	if expected := `{"url":"url"}`; string(body) != expected {
+		t.Fatalf("expected %s, got %s", expected, body)
+	}
+
+	if typeName := header.Get("Ce-Type"); typeName != "com.example.deep-link" {
+		t.Fatalf("expected Ce-Type com.example.deep-link, got %s", typeName)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header = header
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestCloudEvent_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		err   string
+	}{
+		{
+			name:  "unknown type",
+			event: `{"specversion":"1.0","id":"1","source":"/","type":"com.example.unknown"}`,
+			err:   "poly: unknown TypeName com.example.unknown to unmarshal",
+		},
+		{
+			name:  "missing type",
+			event: `{"specversion":"1.0","id":"1","source":"/"}`,
+			err:   "polycloudevents: missing required attribute type",
+		},
+		{
+			name:  "specversion",
+			event: `{"specversion":"0.3","id":"1","source":"/","type":"com.example.dismiss"}`,
+			err:   `polycloudevents: unsupported specversion "0.3"`,
+		},
+		{
+			name:  "datacontenttype",
+			event: `{"specversion":"1.0","id":"1","source":"/","type":"com.example.dismiss","datacontenttype":"text/xml"}`,
+			err:   "polycloudevents: unsupported datacontenttype text/xml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event Event
+
+			err := json.Unmarshal([]byte(tt.event), &event)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+
+	t.Run("no data", func(t *testing.T) {
+		_, err := json.Marshal(Event{ID: "1", Source: "/"})
+		if err == nil || !strings.Contains(err.Error(), "polycloudevents: missing data") {
+			t.Fatalf("expected missing data error, got %v", err)
+		}
+	})
+}
+
+// ActionOpen is at version 2, version 1 had {"link":"..."}.
+type ActionOpen struct {
+	URL string `json:"url"`
+}
+
+func (ActionOpen) IsAction() {}
+
+func (ActionOpen) TypeName() string {
+	return "com.example.open"
+}
+
+func (ActionOpen) PolyVersion() int {
+	return 2
+}
+
+func init() {
+	poly.RegisterMigration[ActionOpen](1, func(data []byte) ([]byte, error) {
+		var v map[string]any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+
+		v["url"] = v["link"]
+		delete(v, "link")
+
+		return json.Marshal(v)
+	})
+}
+
+type VersionedEvent = polycloudevents.CloudEvent[IsAction, poly.Types1[ActionOpen]]
+
+func TestCloudEvent_Versioned(t *testing.T) {
+	event := VersionedEvent{ID: "1", Source: "/actions", Data: poly.Poly[IsAction, poly.Types1[ActionOpen]]{Value: ActionOpen{URL: "url"}}}
+
+	t.Run("structured", func(t *testing.T) {
+		bOut, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		expected := `{"data":{"url":"url"},"datacontenttype":"application/json","id":"1","polyversion":2,` +
+			`"source":"/actions","specversion":"1.0","type":"com.example.open"}`
+		if string(bOut) != expected {
+			t.Fatalf("expected %s, got %s", expected, bOut)
+		}
+
+		var got VersionedEvent
+
+		if err := json.Unmarshal(bOut, &got); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if !reflect.DeepEqual(event, got) {
+			t.Fatalf("expected %#v, got %#v", event, got)
+		}
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		header := http.Header{}
+
+		body, err := event.WriteBinary(header)
+		if err != nil {
+			t.Fatalf("writing error: %v", err)
+		}
+
+		if version := header.Get("Ce-Polyversion"); version != "2" {
+			t.Fatalf("expected Ce-Polyversion 2, got %s", version)
+		}
+
+		var got VersionedEvent
+
+		if err := got.ReadBinary(header, body); err != nil {
+			t.Fatalf("reading error: %v", err)
+		}
+
+		if !reflect.DeepEqual(event, got) {
+			t.Fatalf("expected %#v, got %#v", event, got)
+		}
+	})
+
+	t.Run("migrated", func(t *testing.T) {
+		var got VersionedEvent
+
+		if err := json.Unmarshal([]byte(`{"specversion":"1.0","id":"1","source":"/actions","type":"com.example.open","data":{"link":"url"}}`), &got); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if !reflect.DeepEqual(event, got) {
+			t.Fatalf("expected %#v, got %#v", event, got)
+		}
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		var got VersionedEvent
+
+		err := json.Unmarshal([]byte(`{"specversion":"1.0","id":"1","source":"/","type":"com.example.open","polyversion":"x"}`), &got)
+		if err == nil || !strings.Contains(err.Error(), "polycloudevents: cannot parse polyversion") {
+			t.Fatalf("expected version error, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,14 @@
+//go:build go1.19
+
+package polyhttp
+
+import (
+	"errors"
+	"net/http"
+)
+
+func isMaxBytesError(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+
+	return errors.As(err, &maxBytesErr)
+}
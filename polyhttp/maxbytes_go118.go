@@ -0,0 +1,8 @@
+//go:build !go1.19
+
+package polyhttp
+
+// http.MaxBytesError is available since Go 1.19, before that the error is only distinguished by its text.
+func isMaxBytesError(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
@@ -0,0 +1,186 @@
+// Package polyhttp provides net/http helpers to decode request bodies into poly.Poly values
+// and dispatch them to handlers of the concrete types.
+package polyhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// DefaultMaxBodySize is the default maximum size of request bodies read by Router.
+const DefaultMaxBodySize = 1 << 20
+
+// Error codes of the error responses of Router.
+const (
+	CodeInvalidBody          = "invalid_body"
+	CodeBodyTooLarge         = "body_too_large"
+	CodeMissingDiscriminator = "missing_discriminator"
+	CodeUnknownType          = "unknown_type"
+	CodeUnhandledType        = "unhandled_type"
+)
+
+// Error is the JSON body of the error responses of Router.
+type Error struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	TypeName string `json:"type,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return "polyhttp: " + e.Message
+}
+
+// Router is an http.Handler that decodes request bodies into poly.Poly[I, T] and dispatches the values
+// to the handlers registered for their types with Handle.
+// Requests which cannot be decoded or dispatched are responded with 400 Bad Request and an Error,
+// or with 413 Request Entity Too Large if the body exceeds MaxBodySize.
+type Router[I any, T poly.Types] struct {
+	// MaxBodySize limits the size of request bodies, DefaultMaxBodySize is used if it is not positive.
+	MaxBodySize int64
+
+	handlers map[string]func(w http.ResponseWriter, r *http.Request, value I)
+}
+
+// NewRouter creates a new Router without handlers.
+func NewRouter[I any, T poly.Types]() *Router[I, T] {
+	return &Router[I, T]{
+		handlers: make(map[string]func(w http.ResponseWriter, r *http.Request, value I)),
+	}
+}
+
+// Handle registers the handler for the values of type V, which must be listed by T as V itself
+// or as its pointer or non-pointer counterpart, e.g. ActionDeepLink for the listed *ActionDeepLink.
+// The decoded values are converted to V, so the handler receives them in its form even if T is wrapped
+// in poly.AsPointers or poly.AsValues.
+// It panics if V is not listed by T or a handler for V is already registered.
+func Handle[V any, I any, T poly.Types](router *Router[I, T], handler func(w http.ResponseWriter, r *http.Request, value V)) {
+	reflectType := reflect.TypeOf((*V)(nil)).Elem()
+
+	var t T
+
+	for _, typ := range t.Types() {
+		if !sameType(typ.ReflectType, reflectType) {
+			continue
+		}
+
+		if _, ok := router.handlers[typ.Name]; ok {
+			panic(fmt.Sprintf("polyhttp: handler for %s is already registered", typ.Name))
+		}
+
+		router.handlers[typ.Name] = func(w http.ResponseWriter, r *http.Request, value I) {
+			handler(w, r, convert[V](value))
+		}
+
+		return
+	}
+
+	panic(fmt.Sprintf("polyhttp: %s is not listed by %T", reflectType, t))
+}
+
+// sameType reports whether a and b are the same type or the pointer and non-pointer forms of a type.
+func sameType(a, b reflect.Type) bool {
+	return a == b || reflect.PointerTo(a) == b || reflect.PointerTo(b) == a
+}
+
+// convert returns the value, which is of V or of its pointer or non-pointer counterpart, as V.
+// A non-pointer is converted to a pointer to its copy.
+func convert[V any](value any) V {
+	if v, ok := value.(V); ok {
+		return v
+	}
+
+	reflectValue := reflect.ValueOf(value)
+
+	if reflectValue.Kind() == reflect.Pointer {
+		return reflectValue.Elem().Interface().(V) //nolint:forcetypeassert
+	}
+
+	ptr := reflect.New(reflectValue.Type())
+	ptr.Elem().Set(reflectValue)
+
+	return ptr.Interface().(V) //nolint:forcetypeassert
+}
+
+// ServeHTTP implements the http.Handler interface for Router.
+func (router *Router[I, T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	value, typeName, err := router.decode(w, r)
+	if err != nil {
+		WriteError(w, err)
+
+		return
+	}
+
+	router.handlers[typeName](w, r, value)
+}
+
+func (router *Router[I, T]) decode(w http.ResponseWriter, r *http.Request) (I, string, *Error) {
+	var zero I
+
+	maxBodySize := router.MaxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if isMaxBytesError(err) {
+		return zero, "", &Error{Code: CodeBodyTooLarge, Message: fmt.Sprintf("body exceeds %d bytes", maxBodySize)}
+	}
+
+	if err != nil {
+		return zero, "", &Error{Code: CodeInvalidBody, Message: fmt.Sprintf("cannot read body: %v", err)}
+	}
+
+	var discriminator struct {
+		TypeName string `json:"type"`
+	}
+
+	if err := json.Unmarshal(body, &discriminator); err != nil {
+		return zero, "", &Error{Code: CodeInvalidBody, Message: fmt.Sprintf("cannot unmarshal discriminator 'type': %v", err)}
+	}
+
+	typeName := discriminator.TypeName
+
+	if typeName == "" {
+		return zero, "", &Error{Code: CodeMissingDiscriminator, Message: "missing discriminator 'type'"}
+	}
+
+	if _, ok := poly.LookupType[T](typeName); !ok {
+		return zero, "", &Error{Code: CodeUnknownType, Message: "unknown type " + typeName, TypeName: typeName}
+	}
+
+	if _, ok := router.handlers[typeName]; !ok {
+		return zero, "", &Error{Code: CodeUnhandledType, Message: "unhandled type " + typeName, TypeName: typeName}
+	}
+
+	var p poly.Poly[I, T]
+
+	if err := json.Unmarshal(body, &p); err != nil {
+		return zero, "", &Error{Code: CodeInvalidBody, Message: err.Error(), TypeName: typeName}
+	}
+
+	return p.Value, typeName, nil
+}
+
+// WriteError responds with 400 Bad Request, or 413 Request Entity Too Large for CodeBodyTooLarge, and the error as JSON.
+// Errors other than *Error are written with the CodeInvalidBody code.
+func WriteError(w http.ResponseWriter, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Code: CodeInvalidBody, Message: err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if e.Code == CodeBodyTooLarge {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	} else {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+
+	_ = json.NewEncoder(w).Encode(e)
+}
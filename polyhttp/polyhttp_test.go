@@ -0,0 +1,250 @@
+package polyhttp_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polyhttp"
+)
+
+type IsAction interface {
+	IsAction()
+}
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) IsAction() {}
+
+func (ActionDismiss) TypeName() string {
+	return "dismiss"
+}
+
+type ActionDeepLink struct {
+	URL string `json:"url"`
+}
+
+func (*ActionDeepLink) IsAction() {}
+
+func (*ActionDeepLink) TypeName() string {
+	return "deep-link"
+}
+
+type ActionNotify struct{}
+
+func (ActionNotify) IsAction() {}
+
+func (ActionNotify) TypeName() string {
+	return "notify"
+}
+
+type ActionReload struct{}
+
+func (ActionReload) IsAction() {}
+
+func (ActionReload) TypeName() string {
+	return "reload"
+}
+
+type Types = poly.Types3[ActionDismiss, *ActionDeepLink, ActionNotify]
+
+func newRouter() *polyhttp.Router[IsAction, Types] {
+	router := polyhttp.NewRouter[IsAction, Types]()
+
+	polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, _ ActionDismiss) {
+		fmt.Fprint(w, "dismissed")
+	})
+
+	polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, action *ActionDeepLink) {
+		fmt.Fprint(w, "opened "+action.URL)
+	})
+
+	return router
+}
+
+func TestRouter(t *testing.T) {
+	server := httptest.NewServer(newRouter())
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		resp   string
+	}{
+		{name: "dismiss", body: `{"type":"dismiss"}`, status: http.StatusOK, resp: "dismissed"},
+		{name: "deep link", body: `{"type":"deep-link","url":"url"}`, status: http.StatusOK, resp: "opened url"},
+		{
+			name:   "missing discriminator",
+			body:   `{"url":"url"}`,
+			status: http.StatusBadRequest,
+			resp:   `{"code":"missing_discriminator","message":"missing discriminator 'type'"}`,
+		},
+		{
+			name:   "unknown type",
+			body:   `{"type":"unknown"}`,
+			status: http.StatusBadRequest,
+			resp:   `{"code":"unknown_type","message":"unknown type unknown","type":"unknown"}`,
+		},
+		{
+			name:   "unhandled type",
+			body:   `{"type":"notify"}`,
+			status: http.StatusBadRequest,
+			resp:   `{"code":"unhandled_type","message":"unhandled type notify","type":"notify"}`,
+		},
+		{
+			name:   "invalid JSON",
+			body:   `{"type":`,
+			status: http.StatusBadRequest,
+			resp:   `"code":"invalid_body"`,
+		},
+		{
+			name:   "invalid value",
+			body:   `{"type":"deep-link","url":1}`,
+			status: http.StatusBadRequest,
+			resp:   `"code":"invalid_body"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(server.URL, "application/json", strings.NewReader(tt.body)) //nolint:noctx
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+
+			if body := readAll(t, resp); !strings.Contains(body, tt.resp) {
+				t.Fatalf("expected %s in %s", tt.resp, body)
+			}
+		})
+	}
+}
+
+func TestRouter_MaxBodySize(t *testing.T) {
+	router := newRouter()
+	router.MaxBodySize = 8
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"type":"dismiss"}`)))
+
+	var e polyhttp.Error
+	if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if w.Code != http.StatusRequestEntityTooLarge || e.Code != polyhttp.CodeBodyTooLarge || e.Message != "body exceeds 8 bytes" {
+		t.Fatalf("expected body too large error, got %d %#v", w.Code, e)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	t.Run("not listed", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic")
+			}
+		}()
+
+		polyhttp.Handle(newRouter(), func(http.ResponseWriter, *http.Request, ActionReload) {})
+	})
+
+	t.Run("counterpart duplicate", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic")
+			}
+		}()
+
+		polyhttp.Handle(newRouter(), func(http.ResponseWriter, *http.Request, *ActionDismiss) {})
+	})
+
+	t.Run("as pointers", func(t *testing.T) {
+		router := polyhttp.NewRouter[IsAction, poly.AsPointers[Types]]()
+
+		polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, _ ActionDismiss) {
+			fmt.Fprint(w, "dismissed")
+		})
+
+		polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, action ActionDeepLink) {
+			fmt.Fprint(w, "opened "+action.URL)
+		})
+
+		polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, _ *ActionNotify) {
+			fmt.Fprint(w, "notified")
+		})
+
+		serve(t, router, map[string]string{
+			`{"type":"dismiss"}`:               "dismissed",
+			`{"type":"deep-link","url":"url"}`: "opened url",
+			`{"type":"notify"}`:                "notified",
+		})
+	})
+
+	t.Run("as values", func(t *testing.T) {
+		// ActionDeepLink implements IsAction only as a pointer, so the values are held as any
+		router := polyhttp.NewRouter[any, poly.AsValues[Types]]()
+
+		polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, _ *ActionDismiss) {
+			fmt.Fprint(w, "dismissed")
+		})
+
+		polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, action *ActionDeepLink) {
+			fmt.Fprint(w, "opened "+action.URL)
+		})
+
+		polyhttp.Handle(router, func(w http.ResponseWriter, _ *http.Request, _ ActionNotify) {
+			fmt.Fprint(w, "notified")
+		})
+
+		serve(t, router, map[string]string{
+			`{"type":"dismiss"}`:               "dismissed",
+			`{"type":"deep-link","url":"url"}`: "opened url",
+			`{"type":"notify"}`:                "notified",
+		})
+	})
+
+	t.Run("duplicate", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected panic")
+			}
+		}()
+
+		polyhttp.Handle(newRouter(), func(http.ResponseWriter, *http.Request, ActionDismiss) {})
+	})
+}
+
+// serve checks the responses of the handler to the request bodies.
+func serve(t *testing.T, handler http.Handler, responses map[string]string) {
+	t.Helper()
+
+	for body, expected := range responses {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+		if w.Code != http.StatusOK || w.Body.String() != expected {
+			t.Fatalf("expected %s for %s, got %d %s", expected, body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	var body strings.Builder
+	if _, err := io.Copy(&body, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	return body.String()
+}
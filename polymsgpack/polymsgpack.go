@@ -0,0 +1,165 @@
+// Package polymsgpack allows polymorphic encoding and decoding of MessagePack with github.com/vmihailenco/msgpack/v5,
+// using the 'type' entry of the map as the discriminator.
+package polymsgpack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// Poly wraps poly.Poly and additionally implements the msgpack.CustomEncoder and msgpack.CustomDecoder interfaces.
+// The value is encoded and decoded with the settings of the msgpack.Encoder and msgpack.Decoder, e.g. SetCustomStructTag.
+type Poly[I any, T poly.Types] struct {
+	poly.Poly[I, T]
+}
+
+// EncodeMsgpack implements the msgpack.CustomEncoder interface for Poly.
+// It encodes the underlying value as a map along with its TypeName as a discriminator entry.
+func (p Poly[I, T]) EncodeMsgpack(enc *msgpack.Encoder) error {
+	typ, ok, err := p.Encode()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return enc.EncodeNil()
+	}
+
+	var buf bytes.Buffer
+
+	// A copy of enc keeps its settings, but writes the value to buf to add the discriminator entry to its map.
+	implEnc := *enc
+	implEnc.ResetWriter(&buf)
+
+	if err := implEnc.Encode(p.Value); err != nil {
+		return fmt.Errorf("polymsgpack: cannot marshal value %v: %w", p.Value, err)
+	}
+
+	implData := buf.Bytes()
+
+	n, headLen, err := mapHead(implData)
+	if err != nil {
+		return fmt.Errorf("polymsgpack: expected MessagePack map for %T: %w", p.Value, err)
+	}
+
+	if err := enc.EncodeMapLen(n + 1); err != nil {
+		return err
+	}
+
+	if err := enc.EncodeString("type"); err != nil {
+		return err
+	}
+
+	if err := enc.EncodeString(typ.Name); err != nil {
+		return err
+	}
+
+	return enc.Encode(msgpack.RawMessage(implData[headLen:]))
+}
+
+// DecodeMsgpack implements the msgpack.CustomDecoder interface for Poly.
+// It decodes the map based on the 'type' discriminator entry to the correct concrete type.
+func (p *Poly[I, T]) DecodeMsgpack(dec *msgpack.Decoder) error {
+	data, err := dec.DecodeRaw()
+	if err != nil {
+		return fmt.Errorf("polymsgpack: cannot decode: %w", err)
+	}
+
+	if len(data) == 1 && data[0] == msgpcode.Nil {
+		var zero I
+
+		p.Value = zero
+
+		return nil
+	}
+
+	typeName, implData, err := discriminator(data)
+	if err != nil {
+		return fmt.Errorf("polymsgpack: cannot decode discriminator 'type': %w", err)
+	}
+
+	return p.Decode(typeName, func(ptr any) error {
+		// A copy of dec keeps its settings, but reads the value from implData.
+		implDec := *dec
+		implDec.ResetReader(bytes.NewReader(implData))
+
+		return implDec.Decode(ptr)
+	})
+}
+
+// discriminator returns the 'type' entry of the MessagePack map in data and the map without it,
+// so that the value can be decoded by a Decoder that disallows unknown fields.
+func discriminator(data []byte) (string, []byte, error) {
+	r := bytes.NewReader(data)
+	dec := msgpack.NewDecoder(r)
+
+	n, err := dec.DecodeMapLen()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		typeName string
+		entries  []byte
+	)
+
+	implLen := n
+
+	for i := 0; i < n; i++ {
+		start := len(data) - r.Len()
+
+		key, err := dec.DecodeString()
+		if err != nil {
+			return "", nil, err
+		}
+
+		if key == "type" {
+			if typeName, err = dec.DecodeString(); err != nil {
+				return "", nil, err
+			}
+
+			implLen--
+
+			continue
+		}
+
+		if err := dec.Skip(); err != nil {
+			return "", nil, err
+		}
+
+		entries = append(entries, data[start:len(data)-r.Len()]...)
+	}
+
+	var buf bytes.Buffer
+
+	if err := msgpack.NewEncoder(&buf).EncodeMapLen(implLen); err != nil {
+		return "", nil, err
+	}
+
+	return typeName, append(buf.Bytes(), entries...), nil
+}
+
+// mapHead returns the number of entries of the MessagePack map in data and the length of its head.
+func mapHead(data []byte) (int, int, error) {
+	if len(data) == 0 {
+		return 0, 0, errors.New("empty data")
+	}
+
+	switch code := data[0]; {
+	case msgpcode.IsFixedMap(code):
+		return int(code & msgpcode.FixedMapMask), 1, nil
+	case code == msgpcode.Map16 && len(data) >= 3:
+		return int(binary.BigEndian.Uint16(data[1:])), 3, nil
+	case code == msgpcode.Map32 && len(data) >= 5:
+		return int(binary.BigEndian.Uint32(data[1:])), 5, nil
+	default:
+		return 0, 0, fmt.Errorf("got code %#x", code)
+	}
+}
@@ -0,0 +1,356 @@
+package polymsgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polymsgpack"
+)
+
+type IsCommand interface {
+	IsCommand()
+}
+
+type CommandReboot struct{}
+
+func (CommandReboot) IsCommand() {}
+
+func (CommandReboot) TypeName() string {
+	return "reboot"
+}
+
+type CommandBrightness struct {
+	Level int    `msgpack:"level"`
+	Zone  string `msgpack:"zone,omitempty"`
+	Fade  int64  `msgpack:"fade,omitempty"`
+}
+
+func (*CommandBrightness) IsCommand() {}
+
+func (*CommandBrightness) TypeName() string {
+	return "brightness"
+}
+
+// CommandLabels is a map variant to check the map heads of any size.
+type CommandLabels map[string]int
+
+func (CommandLabels) IsCommand() {}
+
+func (CommandLabels) TypeName() string {
+	return "labels"
+}
+
+// CommandPing is not encoded as a map, so it cannot have a discriminator entry.
+type CommandPing string
+
+func (CommandPing) IsCommand() {}
+
+func (CommandPing) TypeName() string {
+	return "ping"
+}
+
+type CommandTypes = poly.Types4[CommandReboot, *CommandBrightness, CommandLabels, CommandPing]
+
+type Command = polymsgpack.Poly[IsCommand, CommandTypes]
+
+func NewCommand(v IsCommand) Command {
+	return Command{Poly: poly.Poly[IsCommand, CommandTypes]{Value: v}}
+}
+
+type Batch struct {
+	Commands []Command `msgpack:"commands"`
+	Fallback *Command  `msgpack:"fallback"`
+}
+
+func TestPoly_Msgpack(t *testing.T) {
+	in := Batch{
+		Commands: []Command{
+			NewCommand(CommandReboot{}),
+			NewCommand(&CommandBrightness{Level: 80, Zone: "hall"}),
+			{},
+		},
+	}
+
+	data, err := msgpack.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	var out Batch
+
+	if err := msgpack.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if got, ok := out.Commands[0].Value.(CommandReboot); !ok {
+		t.Fatalf("expected CommandReboot, got %T", got)
+	}
+
+	if got, ok := out.Commands[1].Value.(*CommandBrightness); !ok || got.Level != 80 || got.Zone != "hall" {
+		t.Fatalf("expected *CommandBrightness, got %#v", out.Commands[1].Value)
+	}
+
+	if out.Commands[2].Value != nil {
+		t.Fatalf("expected nil, got %#v", out.Commands[2].Value)
+	}
+
+	if out.Fallback != nil {
+		t.Fatalf("expected nil, got %#v", out.Fallback)
+	}
+
+	var raw struct {
+		Commands []map[string]any `msgpack:"commands"`
+	}
+
+	if err := msgpack.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if raw.Commands[0]["type"] != "reboot" || raw.Commands[1]["type"] != "brightness" || raw.Commands[1]["zone"] != "hall" {
+		t.Fatalf("expected discriminator entries, got %v", raw.Commands)
+	}
+}
+
+// BrightnessJSON only has json tags, to be encoded with a custom struct tag.
+type BrightnessJSON struct {
+	Level int `json:"level"`
+}
+
+func (BrightnessJSON) IsCommand() {}
+
+func (BrightnessJSON) TypeName() string {
+	return "brightness"
+}
+
+func TestPoly_Msgpack_settings(t *testing.T) {
+	t.Run("compact ints", func(t *testing.T) {
+		var buf bytes.Buffer
+
+		enc := msgpack.NewEncoder(&buf)
+		enc.UseCompactInts(true)
+
+		if err := enc.Encode(NewCommand(&CommandBrightness{Level: 80, Fade: 500})); err != nil {
+			t.Fatalf("encoding error: %v", err)
+		}
+
+		// A compact int64 is a uint16 instead of an int64.
+		if fade := []byte{msgpcode.FixedStrLow | 4, 'f', 'a', 'd', 'e', msgpcode.Uint16, 0x01, 0xf4}; !bytes.Contains(buf.Bytes(), fade) {
+			t.Fatalf("expected compact fade, got %x", buf.Bytes())
+		}
+	})
+
+	t.Run("custom struct tag", func(t *testing.T) {
+		type Command = polymsgpack.Poly[IsCommand, poly.Types1[BrightnessJSON]]
+
+		var buf bytes.Buffer
+
+		enc := msgpack.NewEncoder(&buf)
+		enc.SetCustomStructTag("json")
+
+		if err := enc.Encode(Command{Poly: poly.Poly[IsCommand, poly.Types1[BrightnessJSON]]{Value: BrightnessJSON{Level: 30}}}); err != nil {
+			t.Fatalf("encoding error: %v", err)
+		}
+
+		var raw map[string]any
+
+		if err := msgpack.Unmarshal(buf.Bytes(), &raw); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if _, ok := raw["level"]; !ok || raw["type"] != "brightness" {
+			t.Fatalf("expected level and type entries, got %v", raw)
+		}
+
+		dec := msgpack.NewDecoder(&buf)
+		dec.SetCustomStructTag("json")
+
+		var command Command
+
+		if err := dec.Decode(&command); err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if command.Value != (BrightnessJSON{Level: 30}) {
+			t.Fatalf("expected BrightnessJSON, got %#v", command.Value)
+		}
+	})
+
+	t.Run("disallow unknown fields", func(t *testing.T) {
+		decode := func(entries map[string]any) (Command, error) {
+			data, err := msgpack.Marshal(entries)
+			if err != nil {
+				t.Fatalf("marshaling error: %v", err)
+			}
+
+			dec := msgpack.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields(true)
+
+			var command Command
+
+			return command, dec.Decode(&command)
+		}
+
+		// The discriminator entry is not a field of the value.
+		command, err := decode(map[string]any{"type": "brightness", "level": 80})
+		if err != nil {
+			t.Fatalf("decoding error: %v", err)
+		}
+
+		if got, ok := command.Value.(*CommandBrightness); !ok || got.Level != 80 {
+			t.Fatalf("expected *CommandBrightness, got %#v", command.Value)
+		}
+
+		_, err = decode(map[string]any{"type": "brightness", "level": 80, "color": "red"})
+		if err == nil || !strings.Contains(err.Error(), "unknown field") {
+			t.Fatalf("expected unknown field error, got %v", err)
+		}
+	})
+}
+
+func TestPoly_Msgpack_mapHead(t *testing.T) {
+	for _, tc := range []struct {
+		entries int
+		code    byte
+	}{
+		{entries: 0, code: msgpcode.FixedMapLow | 1},
+		{entries: 14, code: msgpcode.FixedMapLow | 15},
+		{entries: 15, code: msgpcode.Map16},
+		{entries: 16, code: msgpcode.Map16},
+		{entries: 65534, code: msgpcode.Map16},
+		{entries: 65535, code: msgpcode.Map32},
+		{entries: 65536, code: msgpcode.Map32},
+	} {
+		t.Run(strconv.Itoa(tc.entries), func(t *testing.T) {
+			labels := make(CommandLabels, tc.entries)
+
+			for i := 0; i < tc.entries; i++ {
+				labels[strconv.Itoa(i)] = i
+			}
+
+			data, err := msgpack.Marshal(NewCommand(labels))
+			if err != nil {
+				t.Fatalf("marshaling error: %v", err)
+			}
+
+			if data[0] != tc.code {
+				t.Fatalf("expected head %#x, got %#x", tc.code, data[0])
+			}
+
+			var command Command
+
+			if err := msgpack.Unmarshal(data, &command); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+
+			if got, ok := command.Value.(CommandLabels); !ok || len(got) != tc.entries || got["0"] != labels["0"] {
+				t.Fatalf("expected CommandLabels of %d entries, got %d", tc.entries, len(got))
+			}
+		})
+	}
+}
+
+func TestPoly_Msgpack_patch(t *testing.T) {
+	brightness := &CommandBrightness{Level: 80, Zone: "hall"}
+	command := NewCommand(brightness)
+
+	data, err := msgpack.Marshal(map[string]int{"level": 20})
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	if err := msgpack.Unmarshal(data, &command); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if command.Value != brightness || brightness.Level != 20 || brightness.Zone != "hall" {
+		t.Fatalf("expected patched *CommandBrightness, got %#v", command.Value)
+	}
+}
+
+func TestPoly_Msgpack_errors(t *testing.T) {
+	t.Run("missing discriminator", func(t *testing.T) {
+		var command Command
+
+		data, err := msgpack.Marshal(map[string]int{"level": 20})
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		err = msgpack.Unmarshal(data, &command)
+		if err == nil || !strings.Contains(err.Error(), "missing discriminator") {
+			t.Fatalf("expected missing discriminator error, got %v", err)
+		}
+	})
+
+	t.Run("not a map", func(t *testing.T) {
+		var command Command
+
+		data, err := msgpack.Marshal("reboot")
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		err = msgpack.Unmarshal(data, &command)
+		if err == nil || !strings.Contains(err.Error(), "polymsgpack: cannot decode discriminator 'type'") {
+			t.Fatalf("expected discriminator error, got %v", err)
+		}
+
+		_, err = msgpack.Marshal(NewCommand(CommandPing("pong")))
+		if err == nil || !strings.Contains(err.Error(), "polymsgpack: expected MessagePack map for polymsgpack_test.CommandPing") {
+			t.Fatalf("expected MessagePack map error, got %v", err)
+		}
+	})
+
+	t.Run("unknown decode", func(t *testing.T) {
+		var command Command
+
+		data, err := msgpack.Marshal(map[string]string{"type": "shutdown"})
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		err = msgpack.Unmarshal(data, &command)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName shutdown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
+
+// CommandChecked checks its Zone before marshaling.
+type CommandChecked struct {
+	Zone string `msgpack:"zone"`
+}
+
+func (CommandChecked) IsCommand() {}
+
+func (CommandChecked) TypeName() string {
+	return "checked"
+}
+
+func (v CommandChecked) BeforePolyMarshal() error {
+	if v.Zone == "" {
+		return errors.New("missing zone")
+	}
+
+	return nil
+}
+
+func TestPoly_Msgpack_beforeMarshal(t *testing.T) {
+	type Checked = polymsgpack.Poly[IsCommand, poly.Types1[CommandChecked]]
+
+	if _, err := msgpack.Marshal(Checked{Poly: poly.Poly[IsCommand, poly.Types1[CommandChecked]]{Value: CommandChecked{Zone: "hall"}}}); err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	_, err := msgpack.Marshal(Checked{Poly: poly.Poly[IsCommand, poly.Types1[CommandChecked]]{Value: CommandChecked{}}})
+	if err == nil || !strings.Contains(err.Error(), "poly: cannot marshal checked: missing zone") {
+		t.Fatalf("expected BeforePolyMarshal error, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Package polysql allows storing poly.Poly values in SQL databases as JSON, e.g. in PostgreSQL JSONB columns.
+//
+//	var action Action
+//
+//	_, err := db.Exec("INSERT INTO actions (action) VALUES ($1)", polysql.JSON(&action))
+//	err = db.QueryRow("SELECT action FROM actions").Scan(polysql.JSON(&action))
+package polysql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// ScanValuer is both sql.Scanner and driver.Valuer.
+type ScanValuer interface {
+	sql.Scanner
+	driver.Valuer
+}
+
+// JSON returns an adapter that scans and stores the Poly pointed to by p as JSON.
+// A nil value is stored as NULL, and NULL is scanned as a nil value.
+// A nil p is stored as NULL, while scanning into it returns an error.
+func JSON[I any, T poly.Types](p *poly.Poly[I, T]) ScanValuer {
+	return jsonAdapter[I, T]{p: p}
+}
+
+type jsonAdapter[I any, T poly.Types] struct {
+	p *poly.Poly[I, T]
+}
+
+// Scan implements the sql.Scanner interface.
+// It unmarshals the JSON from a []byte or string source, following the same rules as poly.Poly.UnmarshalJSON.
+func (a jsonAdapter[I, T]) Scan(src any) error {
+	if a.p == nil {
+		return errors.New("polysql: cannot scan into nil *poly.Poly")
+	}
+
+	var data []byte
+
+	switch src := src.(type) {
+	case nil:
+		var zero I
+
+		a.p.Value = zero
+
+		return nil
+	case []byte:
+		data = src
+	case string:
+		data = []byte(src)
+	default:
+		return fmt.Errorf("polysql: cannot scan %T", src)
+	}
+
+	if err := json.Unmarshal(data, a.p); err != nil {
+		return fmt.Errorf("polysql: cannot scan: %w", err)
+	}
+
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+// It marshals the Poly to a JSON string, following the same rules as poly.Poly.MarshalJSON.
+func (a jsonAdapter[I, T]) Value() (driver.Value, error) {
+	if a.p == nil {
+		return nil, nil
+	}
+
+	if _, ok, err := a.p.ResolveType(); err != nil || !ok {
+		return nil, err
+	}
+
+	data, err := json.Marshal(a.p)
+	if err != nil {
+		return nil, fmt.Errorf("polysql: cannot get value: %w", err)
+	}
+
+	return string(data), nil
+}
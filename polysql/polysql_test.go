@@ -0,0 +1,265 @@
+package polysql_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polysql"
+)
+
+type IsAction interface {
+	IsAction()
+}
+
+type ActionDismiss struct{}
+
+func (ActionDismiss) IsAction() {}
+
+func (ActionDismiss) TypeName() string {
+	return "dismiss"
+}
+
+type ActionDeepLink struct {
+	URL string `json:"url"`
+}
+
+func (ActionDeepLink) IsAction() {}
+
+func (ActionDeepLink) TypeName() string {
+	return "deep-link"
+}
+
+type Action = poly.Poly[IsAction, poly.Types2[ActionDismiss, ActionDeepLink]]
+
+// fakeDriver stores the values of all "INSERT" statements in a single column
+// and returns them for all other statements.
+type fakeDriver struct {
+	mu     sync.Mutex
+	values []driver.Value
+	asText bool // return the stored values as strings instead of []byte
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	return fakeConn{d: d}, nil
+}
+
+type fakeConn struct {
+	d *fakeDriver
+}
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{d: c.d, insert: strings.HasPrefix(query, "INSERT")}, nil
+}
+
+func (fakeConn) Close() error {
+	return nil
+}
+
+func (fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeStmt struct {
+	d      *fakeDriver
+	insert bool
+}
+
+func (fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	if s.insert {
+		return 1
+	}
+
+	return 0
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	s.d.values = append(s.d.values, args[0])
+
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	values := make([]driver.Value, len(s.d.values))
+	for i, value := range s.d.values {
+		if str, ok := value.(string); ok && !s.d.asText {
+			value = []byte(str)
+		}
+
+		values[i] = value
+	}
+
+	return &fakeRows{values: values}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (*fakeRows) Columns() []string {
+	return []string{"action"}
+}
+
+func (*fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	dest[0], r.values = r.values[0], r.values[1:]
+
+	return nil
+}
+
+var fakeDrivers int32
+
+func openDB(t *testing.T, asText bool) *sql.DB {
+	t.Helper()
+
+	d := &fakeDriver{asText: asText}
+	name := fmt.Sprintf("polysql-fake-%d", atomic.AddInt32(&fakeDrivers, 1))
+
+	sql.Register(name, d)
+
+	db, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestJSON(t *testing.T) {
+	for _, asText := range []bool{false, true} {
+		name := "bytes"
+		if asText {
+			name = "string"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			db := openDB(t, asText)
+
+			in := []Action{
+				{Value: ActionDismiss{}},
+				{Value: ActionDeepLink{URL: "url"}},
+				{},
+			}
+
+			for i := range in {
+				if _, err := db.Exec("INSERT INTO actions (action) VALUES (?)", polysql.JSON(&in[i])); err != nil {
+					t.Fatalf("insert error: %v", err)
+				}
+			}
+
+			rows, err := db.Query("SELECT action FROM actions")
+			if err != nil {
+				t.Fatalf("query error: %v", err)
+			}
+
+			defer rows.Close()
+
+			var out []Action
+
+			for rows.Next() {
+				action := Action{Value: ActionDismiss{}}
+
+				if err := rows.Scan(polysql.JSON(&action)); err != nil {
+					t.Fatalf("scan error: %v", err)
+				}
+
+				out = append(out, action)
+			}
+
+			if err := rows.Err(); err != nil {
+				t.Fatalf("rows error: %v", err)
+			}
+
+			if len(out) != len(in) {
+				t.Fatalf("expected %d rows, got %d", len(in), len(out))
+			}
+
+			for i := range in {
+				if in[i].Value != out[i].Value {
+					t.Fatalf("expected %#v, got %#v", in[i].Value, out[i].Value)
+				}
+			}
+		})
+	}
+}
+
+func TestJSON_Value(t *testing.T) {
+	value, err := polysql.JSON(&Action{Value: ActionDeepLink{URL: "url"}}).Value()
+	if err != nil {
+		t.Fatalf("value error: %v", err)
+	}
+
+	if expected := `{"type":"deep-link","url":"url"}`; value != expected {
+		t.Fatalf("expected %s, got %v", expected, value)
+	}
+
+	value, err = polysql.JSON[IsAction, poly.Types2[ActionDismiss, ActionDeepLink]](nil).Value()
+	if err != nil || value != nil {
+		t.Fatalf("expected NULL, got %v, %v", value, err)
+	}
+}
+
+func TestJSON_Scan(t *testing.T) {
+	t.Run("patch", func(t *testing.T) {
+		action := Action{Value: ActionDeepLink{URL: "url"}}
+
+		if err := polysql.JSON(&action).Scan(`{"url":"url-2"}`); err != nil {
+			t.Fatalf("scan error: %v", err)
+		}
+
+		if got := action.Value.(ActionDeepLink); got.URL != "url-2" { //nolint:forcetypeassert
+			t.Fatalf("expected patched ActionDeepLink, got %#v", got)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		err := polysql.JSON[IsAction, poly.Types2[ActionDismiss, ActionDeepLink]](nil).Scan(`{"type":"dismiss"}`)
+		if err == nil || !strings.Contains(err.Error(), "polysql: cannot scan into nil *poly.Poly") {
+			t.Fatalf("expected nil Poly error, got %v", err)
+		}
+	})
+
+	t.Run("unsupported source", func(t *testing.T) {
+		var action Action
+
+		err := polysql.JSON(&action).Scan(42)
+		if err == nil || !strings.Contains(err.Error(), "polysql: cannot scan int") {
+			t.Fatalf("expected cannot scan error, got %v", err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		var action Action
+
+		err := polysql.JSON(&action).Scan([]byte(`{"type":"unknown"}`))
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
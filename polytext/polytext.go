@@ -0,0 +1,310 @@
+// Package polytext allows polymorphic encoding and decoding of poly.Poly values as short text,
+// suitable for command-line flags and environment variables.
+//
+// The text consists of the TypeName of the value optionally followed by a colon and
+// comma-separated key=value parameters that set the fields of the value:
+//
+//	constant
+//	exponential:base=2s,max=1m
+//
+// Keys are matched against the names of the fields in their JSON tags, or the names of the
+// fields themselves. Values are parsed according to the types of the fields: encoding.TextUnmarshaler,
+// time.Duration, strings, booleans and numbers are supported, other types are parsed as JSON.
+// Values containing commas, equal signs or quotes are written as double-quoted Go strings.
+//
+// Like in the JSON encoding of poly.Poly, text without a TypeName (e.g. ":max=2m") patches the current value.
+package polytext
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// Poly wraps poly.Poly and additionally implements the encoding.TextMarshaler and encoding.TextUnmarshaler
+// interfaces. A pointer to Poly implements the flag.Value interface, so it can be used with flag.Var.
+type Poly[I any, T poly.Types] struct {
+	poly.Poly[I, T]
+}
+
+// The reflect.Types of the parameters with their own text format; they are immutable.
+var ( //nolint:gochecknoglobals
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// MarshalText implements the encoding.TextMarshaler interface for Poly.
+// It marshals the TypeName of the underlying value along with its non-zero fields as parameters.
+func (p Poly[I, T]) MarshalText() ([]byte, error) {
+	typ, ok, err := p.Encode()
+	if err != nil || !ok {
+		return nil, err
+	}
+
+	params, err := marshalParams(reflect.ValueOf(p.Value))
+	if err != nil {
+		return nil, fmt.Errorf("polytext: cannot marshal value %v: %w", p.Value, err)
+	}
+
+	if params == "" {
+		return []byte(typ.Name), nil
+	}
+
+	return []byte(typ.Name + ":" + params), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface for Poly.
+// It unmarshals the text based on its TypeName to the correct concrete type.
+func (p *Poly[I, T]) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		var zero I
+
+		p.Value = zero
+
+		return nil
+	}
+
+	typeName, params, _ := strings.Cut(string(text), ":")
+
+	return p.Decode(typeName, func(ptr any) error {
+		return unmarshalParams(params, reflect.ValueOf(ptr).Elem())
+	})
+}
+
+// String implements the flag.Value interface for Poly.
+func (p *Poly[I, T]) String() string {
+	if p == nil {
+		return ""
+	}
+
+	text, err := p.MarshalText()
+	if err != nil {
+		return ""
+	}
+
+	return string(text)
+}
+
+// Set implements the flag.Value interface for Poly.
+func (p *Poly[I, T]) Set(value string) error {
+	return p.UnmarshalText([]byte(value))
+}
+
+func marshalParams(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("expected struct, got %s", v.Type())
+	}
+
+	var params []string
+
+	for _, f := range fields(v.Type()) {
+		fv := v.FieldByIndex(f.index)
+		if fv.IsZero() {
+			continue
+		}
+
+		value, err := formatValue(fv)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", f.name, err)
+		}
+
+		if strings.ContainsAny(value, `,="`) {
+			value = strconv.Quote(value)
+		}
+
+		params = append(params, f.name+"="+value)
+	}
+
+	return strings.Join(params, ","), nil
+}
+
+func unmarshalParams(params string, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		v = v.Elem()
+	}
+
+	if params == "" {
+		return nil
+	}
+
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("parameters are not supported by %s", v.Type())
+	}
+
+	known := fields(v.Type())
+
+	for params != "" {
+		var key, value string
+
+		key, params, _ = strings.Cut(params, "=")
+
+		if strings.HasPrefix(params, `"`) {
+			quoted, err := strconv.QuotedPrefix(params)
+			if err != nil {
+				return fmt.Errorf("parameter %s: %w", key, err)
+			}
+
+			value, _ = strconv.Unquote(quoted)
+			params = strings.TrimPrefix(params[len(quoted):], ",")
+		} else {
+			value, params, _ = strings.Cut(params, ",")
+		}
+
+		f, ok := known.get(key)
+		if !ok {
+			return fmt.Errorf("unknown parameter %s", key)
+		}
+
+		if err := parseValue(value, v.FieldByIndex(f.index)); err != nil {
+			return fmt.Errorf("parameter %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+type field struct {
+	name  string
+	index []int
+}
+
+// fieldList is an ordered set of fields that can also be looked up by name.
+type fieldList []field
+
+func fields(t reflect.Type) fieldList {
+	var list fieldList
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		list = append(list, field{name: name, index: sf.Index})
+	}
+
+	return list
+}
+
+func (l fieldList) get(name string) (field, bool) {
+	for _, f := range l {
+		if f.name == name {
+			return f, true
+		}
+	}
+
+	return field{}, false
+}
+
+func formatValue(v reflect.Value) (string, error) {
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText() //nolint:forcetypeassert
+		if err != nil {
+			return "", err
+		}
+
+		return string(text), nil
+	}
+
+	if v.Type() == durationType {
+		return v.Interface().(time.Duration).String(), nil //nolint:forcetypeassert
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	default:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return "", err
+		}
+
+		return string(data), nil
+	}
+}
+
+func parseValue(value string, v reflect.Value) error {
+	if reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)) //nolint:forcetypeassert
+	}
+
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	default:
+		return json.Unmarshal([]byte(value), v.Addr().Interface())
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package polytext_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polytext"
+)
+
+type IsBackoff interface {
+	IsBackoff()
+}
+
+type BackoffConstant struct{}
+
+func (BackoffConstant) IsBackoff() {}
+
+func (BackoffConstant) TypeName() string {
+	return "constant"
+}
+
+type BackoffExponential struct {
+	Base   time.Duration `json:"base"`
+	Max    time.Duration `json:"max,omitempty"`
+	Factor float64       `json:"factor,omitempty"`
+	Jitter bool
+	Label  string `json:"label,omitempty"`
+}
+
+func (*BackoffExponential) IsBackoff() {}
+
+func (*BackoffExponential) TypeName() string {
+	return "exponential"
+}
+
+type BackoffUnknown struct{}
+
+func (BackoffUnknown) IsBackoff() {}
+
+func (BackoffUnknown) TypeName() string {
+	return "unknown"
+}
+
+type BackoffTypes = poly.Types2[BackoffConstant, *BackoffExponential]
+
+type Backoff = polytext.Poly[IsBackoff, BackoffTypes]
+
+func TestPoly_Text(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected IsBackoff
+	}{
+		{
+			text:     "constant",
+			expected: BackoffConstant{},
+		},
+		{
+			text:     "exponential:base=2s",
+			expected: &BackoffExponential{Base: 2 * time.Second},
+		},
+		{
+			text:     `exponential:base=1s,max=1m0s,factor=1.5,Jitter=true,label="a,b=\"c\""`,
+			expected: &BackoffExponential{Base: time.Second, Max: time.Minute, Factor: 1.5, Jitter: true, Label: `a,b="c"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var backoff Backoff
+
+			if err := backoff.UnmarshalText([]byte(tt.text)); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+
+			if expected, ok := tt.expected.(*BackoffExponential); ok {
+				if got, ok := backoff.Value.(*BackoffExponential); !ok || *got != *expected {
+					t.Fatalf("expected %#v, got %#v", expected, backoff.Value)
+				}
+			} else if backoff.Value != tt.expected {
+				t.Fatalf("expected %#v, got %#v", tt.expected, backoff.Value)
+			}
+
+			text, err := backoff.MarshalText()
+			if err != nil {
+				t.Fatalf("marshaling error: %v", err)
+			}
+
+			if string(text) != tt.text {
+				t.Fatalf("expected %s, got %s", tt.text, text)
+			}
+		})
+	}
+}
+
+func TestPoly_UnmarshalText(t *testing.T) {
+	t.Run("patch", func(t *testing.T) {
+		exponential := &BackoffExponential{Base: time.Second}
+		backoff := Backoff{Poly: poly.Poly[IsBackoff, BackoffTypes]{Value: exponential}}
+
+		if err := backoff.UnmarshalText([]byte(":max=1m")); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if backoff.Value != exponential || exponential.Base != time.Second || exponential.Max != time.Minute {
+			t.Fatalf("expected patched *BackoffExponential, got %#v", backoff.Value)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		backoff := Backoff{Poly: poly.Poly[IsBackoff, BackoffTypes]{Value: BackoffConstant{}}}
+
+		if err := backoff.UnmarshalText(nil); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if backoff.Value != nil {
+			t.Fatalf("expected nil, got %#v", backoff.Value)
+		}
+	})
+
+	errorTests := []struct {
+		text string
+		err  string
+	}{
+		{text: ":base=1s", err: "missing discriminator"},
+		{text: "unknown", err: "poly: unknown TypeName unknown to unmarshal"},
+		{text: "exponential:delay=1s", err: "unknown parameter delay"},
+		{text: "exponential:base=fast", err: "parameter base: time: invalid duration"},
+		{text: "constant:base=1s", err: "unknown parameter base"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.text, func(t *testing.T) {
+			var backoff Backoff
+
+			err := backoff.UnmarshalText([]byte(tt.text))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected %q error, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestPoly_MarshalText_unknown(t *testing.T) {
+	backoff := Backoff{Poly: poly.Poly[IsBackoff, BackoffTypes]{Value: BackoffUnknown{}}}
+
+	_, err := backoff.MarshalText()
+	if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown") {
+		t.Fatalf("expected unknown TypeName error, got %v", err)
+	}
+}
+
+func TestPoly_Flag(t *testing.T) {
+	backoff := Backoff{Poly: poly.Poly[IsBackoff, BackoffTypes]{Value: BackoffConstant{}}}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&backoff, "backoff", "backoff strategy")
+
+	if err := fs.Parse([]string{"--backoff=exponential:base=2s"}); err != nil {
+		t.Fatalf("parsing error: %v", err)
+	}
+
+	if got, ok := backoff.Value.(*BackoffExponential); !ok || got.Base != 2*time.Second {
+		t.Fatalf("expected *BackoffExponential, got %#v", backoff.Value)
+	}
+
+	if got := fs.Lookup("backoff").Value.String(); got != "exponential:base=2s" {
+		t.Fatalf("expected exponential:base=2s, got %s", got)
+	}
+
+	if err := fs.Parse([]string{"--backoff=unknown"}); err == nil {
+		t.Fatalf("expected error for unknown TypeName")
+	}
+}
+
+// BackoffChecked checks its Base before marshaling.
+type BackoffChecked struct {
+	Base string `json:"base"`
+}
+
+func (BackoffChecked) IsBackoff() {}
+
+func (BackoffChecked) TypeName() string {
+	return "checked"
+}
+
+func (v BackoffChecked) BeforePolyMarshal() error {
+	if v.Base == "" {
+		return errors.New("missing base")
+	}
+
+	return nil
+}
+
+func TestPoly_Text_beforeMarshal(t *testing.T) {
+	type Checked = polytext.Poly[IsBackoff, poly.Types1[BackoffChecked]]
+
+	if _, err := (Checked{Poly: poly.Poly[IsBackoff, poly.Types1[BackoffChecked]]{Value: BackoffChecked{Base: "b"}}}).MarshalText(); err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	_, err := Checked{Poly: poly.Poly[IsBackoff, poly.Types1[BackoffChecked]]{Value: BackoffChecked{}}}.MarshalText()
+	if err == nil || !strings.Contains(err.Error(), "poly: cannot marshal checked: missing base") {
+		t.Fatalf("expected BeforePolyMarshal error, got %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+// Package polyxml allows polymorphic encoding and decoding of XML with encoding/xml.
+//
+// The TypeName of the value can be stored either in an attribute of the element, see Poly and Attr,
+// or in the name of the element itself, see Element. Like in the JSON encoding of poly.Poly,
+// an element without the discriminator attribute patches the current value.
+package polyxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// NamespaceXSI is the XML Schema instance namespace of the xsi:type and xsi:nil attributes.
+const NamespaceXSI = "http://www.w3.org/2001/XMLSchema-instance"
+
+// Attribute names the attribute holding the TypeName of Attr.
+type Attribute interface {
+	// XMLAttr returns the name of the attribute and the prefix declared for its namespace when marshaling.
+	XMLAttr() (name xml.Name, prefix string)
+}
+
+// TypeAttr is the Attribute of Poly, the unqualified 'type' attribute.
+type TypeAttr struct{}
+
+func (TypeAttr) XMLAttr() (xml.Name, string) {
+	return xml.Name{Local: "type"}, ""
+}
+
+// XSIType is the 'xsi:type' Attribute of XML Schema instances.
+type XSIType struct{}
+
+func (XSIType) XMLAttr() (xml.Name, string) {
+	return xml.Name{Space: NamespaceXSI, Local: "type"}, "xsi"
+}
+
+// Poly wraps poly.Poly and additionally implements the xml.Marshaler and xml.Unmarshaler interfaces
+// using the unqualified 'type' attribute as the discriminator.
+type Poly[I any, T poly.Types] struct {
+	poly.Poly[I, T]
+}
+
+// MarshalXML implements the xml.Marshaler interface for Poly.
+// It marshals the underlying value along with its TypeName in the 'type' attribute.
+func (p Poly[I, T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalAttr[TypeAttr](p.Poly, e, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface for Poly.
+// It unmarshals the element based on the 'type' attribute to the correct concrete type.
+func (p *Poly[I, T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalAttr[TypeAttr](&p.Poly, d, start)
+}
+
+// Attr is Poly with the discriminator in the attribute named by A, e.g. XSIType:
+//
+//	type Shape = polyxml.Attr[IsShape, poly.Types2[Circle, Rect], polyxml.XSIType]
+//
+// The value of a namespaced attribute is a QName, so its prefix is ignored when unmarshaling,
+// e.g. xsi:type="tns:circle" matches the TypeName circle.
+type Attr[I any, T poly.Types, A Attribute] struct {
+	poly.Poly[I, T]
+}
+
+// MarshalXML implements the xml.Marshaler interface for Attr.
+// It marshals the underlying value along with its TypeName in the attribute named by A.
+func (p Attr[I, T, A]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return marshalAttr[A](p.Poly, e, start)
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface for Attr.
+// It unmarshals the element based on the attribute named by A to the correct concrete type.
+func (p *Attr[I, T, A]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	return unmarshalAttr[A](&p.Poly, d, start)
+}
+
+func marshalAttr[A Attribute, I any, T poly.Types](p poly.Poly[I, T], e *xml.Encoder, start xml.StartElement) error {
+	typ, ok, err := p.Encode()
+	if err != nil || !ok {
+		return err
+	}
+
+	var a A
+
+	name, prefix := a.XMLAttr()
+
+	if name.Space == "" {
+		start.Attr = append(start.Attr, xml.Attr{Name: name, Value: typ.Name})
+	} else {
+		// the prefix is declared explicitly, as encoding/xml would derive an unusual one from the namespace
+		start.Attr = append(start.Attr,
+			xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: name.Space},
+			xml.Attr{Name: xml.Name{Local: prefix + ":" + name.Local}, Value: typ.Name},
+		)
+	}
+
+	if err := e.EncodeElement(p.Value, start); err != nil {
+		return fmt.Errorf("polyxml: cannot marshal value %v: %w", p.Value, err)
+	}
+
+	return nil
+}
+
+func unmarshalAttr[A Attribute, I any, T poly.Types](p *poly.Poly[I, T], d *xml.Decoder, start xml.StartElement) error {
+	if isNil(start) {
+		var zero I
+
+		p.Value = zero
+
+		return d.Skip()
+	}
+
+	var a A
+
+	name, prefix := a.XMLAttr()
+
+	var typeName string
+
+	attrs := make([]xml.Attr, 0, len(start.Attr))
+
+	for _, attr := range start.Attr {
+		if !matchAttr(attr.Name, name, prefix) {
+			attrs = append(attrs, attr)
+
+			continue
+		}
+
+		typeName = attr.Value
+
+		if name.Space != "" {
+			// strip the prefix of the QName
+			typeName = typeName[strings.LastIndex(typeName, ":")+1:]
+		}
+	}
+
+	start.Attr = attrs
+
+	return p.Decode(typeName, func(ptr any) error {
+		return d.DecodeElement(ptr, &start)
+	})
+}
+
+// matchAttr reports whether the attribute has the name. An undeclared prefix is kept by encoding/xml
+// as the namespace, so it is matched as well.
+func matchAttr(attr, name xml.Name, prefix string) bool {
+	if attr.Local != name.Local {
+		return false
+	}
+
+	if name.Space == "" {
+		return attr.Space == ""
+	}
+
+	return attr.Space == name.Space || attr.Space == prefix
+}
+
+// Element wraps poly.Poly and additionally implements the xml.Marshaler and xml.Unmarshaler interfaces
+// using the name of the element as the discriminator.
+// To unmarshal a field with elements of different names, tag it with `xml:",any"`.
+type Element[I any, T poly.Types] struct {
+	poly.Poly[I, T]
+}
+
+// MarshalXML implements the xml.Marshaler interface for Element.
+// It marshals the underlying value as an element named after its TypeName.
+func (p Element[I, T]) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	typ, ok, err := p.Encode()
+	if err != nil || !ok {
+		return err
+	}
+
+	start.Name = xml.Name{Local: typ.Name}
+
+	if err := e.EncodeElement(p.Value, start); err != nil {
+		return fmt.Errorf("polyxml: cannot marshal value %v: %w", p.Value, err)
+	}
+
+	return nil
+}
+
+// UnmarshalXML implements the xml.Unmarshaler interface for Element.
+// It unmarshals the element based on its name to the correct concrete type.
+func (p *Element[I, T]) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	if isNil(start) {
+		var zero I
+
+		p.Value = zero
+
+		return d.Skip()
+	}
+
+	return p.Decode(start.Name.Local, func(ptr any) error {
+		return d.DecodeElement(ptr, &start)
+	})
+}
+
+// isNil reports whether the element is marked with xsi:nil="true".
+func isNil(start xml.StartElement) bool {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == NamespaceXSI && attr.Name.Local == "nil" {
+			return attr.Value == "true" || attr.Value == "1"
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,317 @@
+package polyxml_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polyxml"
+)
+
+type IsShape interface {
+	IsShape()
+}
+
+type Circle struct {
+	Radius int `xml:"radius"`
+}
+
+func (Circle) IsShape() {}
+
+func (Circle) TypeName() string {
+	return "circle"
+}
+
+type Rect struct {
+	Width  int `xml:"width,attr"`
+	Height int `xml:"height,attr"`
+}
+
+func (*Rect) IsShape() {}
+
+func (*Rect) TypeName() string {
+	return "rect"
+}
+
+type Unknown struct{}
+
+func (Unknown) IsShape() {}
+
+func (Unknown) TypeName() string {
+	return "unknown"
+}
+
+type ShapeTypes = poly.Types2[Circle, *Rect]
+
+type Shape = polyxml.Poly[IsShape, ShapeTypes]
+
+type ShapeElement = polyxml.Element[IsShape, ShapeTypes]
+
+type Labeled struct {
+	Kind string `xml:"type,attr"`
+}
+
+func (Labeled) IsShape() {}
+
+func (Labeled) TypeName() string {
+	return "labeled"
+}
+
+type ShapeXSI = polyxml.Attr[IsShape, poly.Types3[Circle, *Rect, Labeled], polyxml.XSIType]
+
+type Drawing struct {
+	XMLName xml.Name `xml:"drawing"`
+	Shapes  []Shape  `xml:"shape"`
+}
+
+type DrawingElements struct {
+	XMLName xml.Name       `xml:"drawing"`
+	Shapes  []ShapeElement `xml:",any"`
+}
+
+func TestPoly_XML(t *testing.T) {
+	in := `<drawing><shape type="circle"><radius>2</radius></shape><shape width="3" height="4" type="rect"></shape></drawing>`
+
+	var drawing Drawing
+
+	if err := xml.Unmarshal([]byte(in), &drawing); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if got, ok := drawing.Shapes[0].Value.(Circle); !ok || got.Radius != 2 {
+		t.Fatalf("expected Circle, got %#v", drawing.Shapes[0].Value)
+	}
+
+	if got, ok := drawing.Shapes[1].Value.(*Rect); !ok || got.Width != 3 || got.Height != 4 {
+		t.Fatalf("expected *Rect, got %#v", drawing.Shapes[1].Value)
+	}
+
+	out, err := xml.Marshal(drawing)
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	if expected := `<drawing><shape type="circle"><radius>2</radius></shape><shape type="rect" width="3" height="4"></shape></drawing>`; string(out) != expected {
+		t.Fatalf("expected %s, got %s", expected, out)
+	}
+}
+
+func TestPoly_UnmarshalXML(t *testing.T) {
+	t.Run("namespaced type", func(t *testing.T) {
+		var shape Shape
+
+		in := `<shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="circle"><radius>1</radius></shape>`
+
+		err := xml.Unmarshal([]byte(in), &shape)
+		if err == nil || !strings.Contains(err.Error(), "missing discriminator") {
+			t.Fatalf("expected missing discriminator error, got %v", err)
+		}
+	})
+
+	t.Run("xsi:nil", func(t *testing.T) {
+		shape := Shape{Poly: poly.Poly[IsShape, ShapeTypes]{Value: Circle{}}}
+
+		in := `<shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:nil="true"></shape>`
+		if err := xml.Unmarshal([]byte(in), &shape); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if shape.Value != nil {
+			t.Fatalf("expected nil, got %#v", shape.Value)
+		}
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		rect := &Rect{Width: 1, Height: 1}
+		shape := Shape{Poly: poly.Poly[IsShape, ShapeTypes]{Value: rect}}
+
+		if err := xml.Unmarshal([]byte(`<shape width="5"></shape>`), &shape); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if shape.Value != rect || rect.Width != 5 || rect.Height != 1 {
+			t.Fatalf("expected patched *Rect, got %#v", shape.Value)
+		}
+	})
+
+	t.Run("missing discriminator", func(t *testing.T) {
+		var shape Shape
+
+		err := xml.Unmarshal([]byte(`<shape><radius>1</radius></shape>`), &shape)
+		if err == nil || !strings.Contains(err.Error(), "missing discriminator") {
+			t.Fatalf("expected missing discriminator error, got %v", err)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		var shape Shape
+
+		err := xml.Unmarshal([]byte(`<shape type="unknown"></shape>`), &shape)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
+
+func TestAttr_XSIType(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		var shape ShapeXSI
+
+		in := `<shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="circle"><radius>1</radius></shape>`
+		if err := xml.Unmarshal([]byte(in), &shape); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := shape.Value.(Circle); !ok || got.Radius != 1 {
+			t.Fatalf("expected Circle, got %#v", shape.Value)
+		}
+
+		var b strings.Builder
+
+		if err := xml.NewEncoder(&b).EncodeElement(shape, xml.StartElement{Name: xml.Name{Local: "shape"}}); err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		out := []byte(b.String())
+
+		expected := `<shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:type="circle"><radius>1</radius></shape>`
+		if string(out) != expected {
+			t.Fatalf("expected %s, got %s", expected, out)
+		}
+
+		var again ShapeXSI
+
+		if err := xml.Unmarshal(out, &again); err != nil {
+			t.Fatalf("unmarshaling marshaled error: %v", err)
+		}
+
+		if got, ok := again.Value.(Circle); !ok || got.Radius != 1 {
+			t.Fatalf("expected Circle, got %#v", again.Value)
+		}
+	})
+
+	t.Run("QName", func(t *testing.T) {
+		var shape ShapeXSI
+
+		in := `<shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:tns="urn:shapes" xsi:type="tns:rect" width="2"></shape>`
+		if err := xml.Unmarshal([]byte(in), &shape); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := shape.Value.(*Rect); !ok || got.Width != 2 {
+			t.Fatalf("expected *Rect, got %#v", shape.Value)
+		}
+	})
+
+	t.Run("other type attributes", func(t *testing.T) {
+		var shape ShapeXSI
+
+		in := `<shape xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:foo="urn:foo" foo:type="circle" xsi:type="labeled" type="label"></shape>`
+		if err := xml.Unmarshal([]byte(in), &shape); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := shape.Value.(Labeled); !ok || got.Kind != "label" {
+			t.Fatalf("expected Labeled, got %#v", shape.Value)
+		}
+	})
+
+	t.Run("undeclared prefix", func(t *testing.T) {
+		var shape ShapeXSI
+
+		if err := xml.Unmarshal([]byte(`<shape xsi:type="circle"></shape>`), &shape); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if _, ok := shape.Value.(Circle); !ok {
+			t.Fatalf("expected Circle, got %#v", shape.Value)
+		}
+	})
+}
+
+func TestElement_XML(t *testing.T) {
+	in := `<drawing><circle><radius>2</radius></circle><rect width="3" height="4"></rect></drawing>`
+
+	var drawing DrawingElements
+
+	if err := xml.Unmarshal([]byte(in), &drawing); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if got, ok := drawing.Shapes[0].Value.(Circle); !ok || got.Radius != 2 {
+		t.Fatalf("expected Circle, got %#v", drawing.Shapes[0].Value)
+	}
+
+	if got, ok := drawing.Shapes[1].Value.(*Rect); !ok || got.Width != 3 || got.Height != 4 {
+		t.Fatalf("expected *Rect, got %#v", drawing.Shapes[1].Value)
+	}
+
+	out, err := xml.Marshal(drawing)
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	if string(out) != in {
+		t.Fatalf("expected %s, got %s", in, out)
+	}
+
+	t.Run("unknown marshal", func(t *testing.T) {
+		shape := ShapeElement{Poly: poly.Poly[IsShape, ShapeTypes]{Value: Unknown{}}}
+
+		_, err := xml.Marshal(shape)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+
+	t.Run("unknown unmarshal", func(t *testing.T) {
+		var shape ShapeElement
+
+		err := xml.Unmarshal([]byte(`<triangle></triangle>`), &shape)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName triangle to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+}
+
+// Checked checks its Label before marshaling.
+type Checked struct {
+	Label string `xml:"label"`
+}
+
+func (Checked) IsShape() {}
+
+func (Checked) TypeName() string {
+	return "checked"
+}
+
+func (v Checked) BeforePolyMarshal() error {
+	if v.Label == "" {
+		return errors.New("missing label")
+	}
+
+	return nil
+}
+
+func TestPoly_XML_beforeMarshal(t *testing.T) {
+	type CheckedTypes = poly.Types1[Checked]
+
+	for name, value := range map[string]any{
+		"poly":    polyxml.Poly[IsShape, CheckedTypes]{Poly: poly.Poly[IsShape, CheckedTypes]{Value: Checked{}}},
+		"attr":    polyxml.Attr[IsShape, CheckedTypes, polyxml.XSIType]{Poly: poly.Poly[IsShape, CheckedTypes]{Value: Checked{}}},
+		"element": polyxml.Element[IsShape, CheckedTypes]{Poly: poly.Poly[IsShape, CheckedTypes]{Value: Checked{}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := xml.Marshal(value)
+			if err == nil || !strings.Contains(err.Error(), "poly: cannot marshal checked: missing label") {
+				t.Fatalf("expected BeforePolyMarshal error, got %v", err)
+			}
+		})
+	}
+
+	if _, err := xml.Marshal(polyxml.Element[IsShape, CheckedTypes]{Poly: poly.Poly[IsShape, CheckedTypes]{Value: Checked{Label: "l"}}}); err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+// Package polyyaml allows polymorphic encoding and decoding of YAML with gopkg.in/yaml.v3,
+// using the 'type' key of the mapping as the discriminator.
+package polyyaml
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// Poly wraps poly.Poly and additionally implements the yaml.Marshaler and yaml.Unmarshaler interfaces.
+type Poly[I any, T poly.Types] struct {
+	poly.Poly[I, T]
+}
+
+// MarshalYAML implements the yaml.Marshaler interface for Poly.
+// It marshals the underlying value along with its TypeName as a discriminator.
+func (p Poly[I, T]) MarshalYAML() (any, error) {
+	typ, ok, err := p.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, nil //nolint:nilnil
+	}
+
+	var node yaml.Node
+
+	if err := node.Encode(p.Value); err != nil {
+		return nil, fmt.Errorf("polyyaml: cannot marshal value %v: %w", p.Value, err)
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("polyyaml: expected YAML mapping for %T, got %s", p.Value, node.ShortTag())
+	}
+
+	if len(node.Content) == 0 {
+		node.Style = 0 // an empty mapping is encoded in the flow style, which is not needed anymore
+	}
+
+	node.Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "type"},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: typ.Name},
+	}, node.Content...)
+
+	return &node, nil
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface for Poly.
+// It unmarshals the YAML based on the 'type' discriminator key to the correct concrete type.
+func (p *Poly[I, T]) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		var zero I
+
+		p.Value = zero
+
+		return nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("polyyaml: expected YAML mapping, got %s", node.ShortTag())
+	}
+
+	var typeName string
+
+	data := *node
+	data.Content = make([]*yaml.Node, 0, len(node.Content))
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+
+		if key.Value != "type" {
+			data.Content = append(data.Content, key, value)
+
+			continue
+		}
+
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("polyyaml: discriminator 'type' must be a scalar, got %s", value.ShortTag())
+		}
+
+		typeName = value.Value
+	}
+
+	return p.Decode(typeName, data.Decode)
+}
@@ -0,0 +1,212 @@
+package polyyaml_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ykalchevskiy/poly"
+	"github.com/ykalchevskiy/poly/polyyaml"
+)
+
+type IsStep interface {
+	IsStep()
+}
+
+type StepWait struct{}
+
+func (StepWait) IsStep() {}
+
+func (StepWait) TypeName() string {
+	return "wait"
+}
+
+type StepRun struct {
+	Command string `yaml:"command"`
+	Retries int    `yaml:"retries,omitempty"`
+}
+
+func (StepRun) IsStep() {}
+
+func (StepRun) TypeName() string {
+	return "run"
+}
+
+type StepDeploy struct {
+	Target string `yaml:"target"`
+}
+
+func (*StepDeploy) IsStep() {}
+
+func (*StepDeploy) TypeName() string {
+	return "deploy"
+}
+
+type StepUnknown struct{}
+
+func (StepUnknown) IsStep() {}
+
+func (StepUnknown) TypeName() string {
+	return "unknown"
+}
+
+type Step = polyyaml.Poly[IsStep, poly.Types3[StepWait, StepRun, *StepDeploy]]
+
+type Pipeline struct {
+	Steps []Step `yaml:"steps"`
+	Final *Step  `yaml:"final"`
+}
+
+func TestPoly_YAML(t *testing.T) {
+	in := `steps:
+    - type: wait
+    - type: run
+      command: make test
+      retries: 2
+    - type: deploy
+      target: prod
+final: null
+`
+
+	var pipeline Pipeline
+
+	if err := yaml.Unmarshal([]byte(in), &pipeline); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if got, ok := pipeline.Steps[0].Value.(StepWait); !ok {
+		t.Fatalf("expected StepWait, got %T", got)
+	}
+
+	if got, ok := pipeline.Steps[1].Value.(StepRun); !ok || got.Command != "make test" || got.Retries != 2 {
+		t.Fatalf("expected StepRun, got %#v", pipeline.Steps[1].Value)
+	}
+
+	if got, ok := pipeline.Steps[2].Value.(*StepDeploy); !ok || got.Target != "prod" {
+		t.Fatalf("expected *StepDeploy, got %#v", pipeline.Steps[2].Value)
+	}
+
+	if pipeline.Final != nil {
+		t.Fatalf("expected nil, got %v", pipeline.Final)
+	}
+
+	out, err := yaml.Marshal(pipeline)
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	if string(out) != in {
+		t.Fatalf("expected %s, got %s", in, out)
+	}
+}
+
+func TestPoly_UnmarshalYAML_patch(t *testing.T) {
+	t.Run("value", func(t *testing.T) {
+		step := Step{Poly: poly.Poly[IsStep, poly.Types3[StepWait, StepRun, *StepDeploy]]{
+			Value: StepRun{Command: "make", Retries: 1},
+		}}
+
+		if err := yaml.Unmarshal([]byte(`command: make lint`), &step); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got := step.Value.(StepRun); got.Command != "make lint" || got.Retries != 1 { //nolint:forcetypeassert
+			t.Fatalf("expected patched StepRun, got %#v", got)
+		}
+	})
+
+	t.Run("pointer", func(t *testing.T) {
+		deploy := &StepDeploy{Target: "dev"}
+		step := Step{Poly: poly.Poly[IsStep, poly.Types3[StepWait, StepRun, *StepDeploy]]{Value: deploy}}
+
+		if err := yaml.Unmarshal([]byte(`{type: deploy, target: prod}`), &step); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if step.Value != deploy || deploy.Target != "prod" {
+			t.Fatalf("expected patched *StepDeploy, got %#v", step.Value)
+		}
+	})
+
+	t.Run("type change", func(t *testing.T) {
+		step := Step{Poly: poly.Poly[IsStep, poly.Types3[StepWait, StepRun, *StepDeploy]]{Value: StepWait{}}}
+
+		if err := yaml.Unmarshal([]byte(`{type: run, command: make}`), &step); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := step.Value.(StepRun); !ok || got.Command != "make" {
+			t.Fatalf("expected StepRun, got %#v", step.Value)
+		}
+	})
+}
+
+func TestPoly_YAML_errors(t *testing.T) {
+	t.Run("missing discriminator", func(t *testing.T) {
+		var step Step
+
+		err := yaml.Unmarshal([]byte(`command: make`), &step)
+		if err == nil || !strings.Contains(err.Error(), "missing discriminator") {
+			t.Fatalf("expected missing discriminator error, got %v", err)
+		}
+	})
+
+	t.Run("unknown unmarshal", func(t *testing.T) {
+		var step Step
+
+		err := yaml.Unmarshal([]byte(`type: unknown`), &step)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown to unmarshal") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+
+	t.Run("unknown marshal", func(t *testing.T) {
+		step := Step{Poly: poly.Poly[IsStep, poly.Types3[StepWait, StepRun, *StepDeploy]]{Value: StepUnknown{}}}
+
+		_, err := yaml.Marshal(step)
+		if err == nil || !strings.Contains(err.Error(), "poly: unknown TypeName unknown") {
+			t.Fatalf("expected unknown TypeName error, got %v", err)
+		}
+	})
+
+	t.Run("not a mapping", func(t *testing.T) {
+		var step Step
+
+		err := yaml.Unmarshal([]byte(`[wait]`), &step)
+		if err == nil || !strings.Contains(err.Error(), "expected YAML mapping") {
+			t.Fatalf("expected mapping error, got %v", err)
+		}
+	})
+}
+
+// StepChecked checks its Command before marshaling.
+type StepChecked struct {
+	Command string `yaml:"command"`
+}
+
+func (StepChecked) IsStep() {}
+
+func (StepChecked) TypeName() string {
+	return "checked"
+}
+
+func (v StepChecked) BeforePolyMarshal() error {
+	if v.Command == "" {
+		return errors.New("missing command")
+	}
+
+	return nil
+}
+
+func TestPoly_YAML_beforeMarshal(t *testing.T) {
+	if _, err := yaml.Marshal(polyyaml.Poly[IsStep, poly.Types1[StepChecked]]{Poly: poly.Poly[IsStep, poly.Types1[StepChecked]]{Value: StepChecked{Command: "c"}}}); err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	_, err := yaml.Marshal(polyyaml.Poly[IsStep, poly.Types1[StepChecked]]{Poly: poly.Poly[IsStep, poly.Types1[StepChecked]]{Value: StepChecked{}}})
+	if err == nil || !strings.Contains(err.Error(), "poly: cannot marshal checked: missing command") {
+		t.Fatalf("expected BeforePolyMarshal error, got %v", err)
+	}
+}
@@ -1,5 +1,7 @@
 package poly
 
+import "fmt"
+
 // TypeList is a recursive type that represents a list of types.
 // It is used to provide a variadic number of types to a generic function.
 type TypeList[First TypeName, Rest Types] struct{}
@@ -133,3 +135,218 @@ func (Types9[T1, T2, T3, T4, T5, T6, T7, T8, T9]) Types() []Type {
 		NewType[T9](),
 	}
 }
+
+// concat returns the concatenation of the lists of types.
+func concat(lists ...[]Type) []Type {
+	var n int
+	for _, list := range lists {
+		n += len(list)
+	}
+
+	types := make([]Type, 0, n)
+	for _, list := range lists {
+		types = append(types, list...)
+	}
+
+	return types
+}
+
+// Concat2 represents the concatenation of two lists of types.
+// It allows declaring more than nine types without nesting TypeList, e.g. Concat2[Types9[...], Types9[...]].
+type Concat2[L1, L2 Types] struct{}
+
+func (Concat2[L1, L2]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+	)
+
+	return concat(l1.Types(), l2.Types())
+}
+
+// Concat3 represents the concatenation of three lists of types.
+type Concat3[L1, L2, L3 Types] struct{}
+
+func (Concat3[L1, L2, L3]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+		l3 L3
+	)
+
+	return concat(l1.Types(), l2.Types(), l3.Types())
+}
+
+// Concat4 represents the concatenation of four lists of types.
+type Concat4[L1, L2, L3, L4 Types] struct{}
+
+func (Concat4[L1, L2, L3, L4]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+		l3 L3
+		l4 L4
+	)
+
+	return concat(l1.Types(), l2.Types(), l3.Types(), l4.Types())
+}
+
+// Concat5 represents the concatenation of five lists of types.
+type Concat5[L1, L2, L3, L4, L5 Types] struct{}
+
+func (Concat5[L1, L2, L3, L4, L5]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+		l3 L3
+		l4 L4
+		l5 L5
+	)
+
+	return concat(l1.Types(), l2.Types(), l3.Types(), l4.Types(), l5.Types())
+}
+
+// Concat6 represents the concatenation of six lists of types.
+type Concat6[L1, L2, L3, L4, L5, L6 Types] struct{}
+
+func (Concat6[L1, L2, L3, L4, L5, L6]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+		l3 L3
+		l4 L4
+		l5 L5
+		l6 L6
+	)
+
+	return concat(l1.Types(), l2.Types(), l3.Types(), l4.Types(), l5.Types(), l6.Types())
+}
+
+// Concat7 represents the concatenation of seven lists of types.
+type Concat7[L1, L2, L3, L4, L5, L6, L7 Types] struct{}
+
+func (Concat7[L1, L2, L3, L4, L5, L6, L7]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+		l3 L3
+		l4 L4
+		l5 L5
+		l6 L6
+		l7 L7
+	)
+
+	return concat(l1.Types(), l2.Types(), l3.Types(), l4.Types(), l5.Types(), l6.Types(), l7.Types())
+}
+
+// Concat8 represents the concatenation of eight lists of types.
+type Concat8[L1, L2, L3, L4, L5, L6, L7, L8 Types] struct{}
+
+func (Concat8[L1, L2, L3, L4, L5, L6, L7, L8]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+		l3 L3
+		l4 L4
+		l5 L5
+		l6 L6
+		l7 L7
+		l8 L8
+	)
+
+	return concat(l1.Types(), l2.Types(), l3.Types(), l4.Types(), l5.Types(), l6.Types(), l7.Types(), l8.Types())
+}
+
+// Concat9 represents the concatenation of nine lists of types.
+type Concat9[L1, L2, L3, L4, L5, L6, L7, L8, L9 Types] struct{}
+
+func (Concat9[L1, L2, L3, L4, L5, L6, L7, L8, L9]) Types() []Type {
+	var (
+		l1 L1
+		l2 L2
+		l3 L3
+		l4 L4
+		l5 L5
+		l6 L6
+		l7 L7
+		l8 L8
+		l9 L9
+	)
+
+	return concat(l1.Types(), l2.Types(), l3.Types(), l4.Types(), l5.Types(), l6.Types(), l7.Types(), l8.Types(), l9.Types())
+}
+
+// Union represents the types listed by A followed by the types listed by B which are not listed by A.
+// If A and B list different types with the same TypeName, both are listed, and Poly returns an error
+// when decoding or encoding, see CheckTypes.
+type Union[A, B Types] struct{}
+
+func (Union[A, B]) Types() []Type {
+	var (
+		a A
+		b B
+	)
+
+	types := append([]Type(nil), a.Types()...)
+
+	for _, typ := range b.Types() {
+		if listed, ok := findType(types, typ.Name); ok && listed.ReflectType == typ.ReflectType {
+			continue
+		}
+
+		types = append(types, typ)
+	}
+
+	return types
+}
+
+// Without represents the types listed by A which are not listed by B.
+type Without[A, B Types] struct{}
+
+func (Without[A, B]) Types() []Type {
+	var (
+		a A
+		b B
+	)
+
+	excluded := b.Types()
+
+	var types []Type
+
+	for _, typ := range a.Types() {
+		if listed, ok := findType(excluded, typ.Name); ok && listed.ReflectType == typ.ReflectType {
+			continue
+		}
+
+		types = append(types, typ)
+	}
+
+	return types
+}
+
+func findType(types []Type, name string) (Type, bool) {
+	for _, typ := range types {
+		if typ.Name == name {
+			return typ, true
+		}
+	}
+
+	return Type{}, false
+}
+
+// CheckTypes returns an error if T lists several types with the same TypeName.
+// Poly returns an error for different types with the same TypeName only when decoding or encoding,
+// so it is intended to be used in tests for the lists combined with TypeList, ConcatN or Union.
+func CheckTypes[T Types]() error {
+	var t T
+
+	types := t.Types()
+
+	for i, typ := range types {
+		if listed, ok := findType(types[:i], typ.Name); ok {
+			return fmt.Errorf("poly: duplicate TypeName %s of %s and %s", typ.Name, listed.ReflectType, typ.ReflectType)
+		}
+	}
+
+	return nil
+}
@@ -1,7 +1,9 @@
 package poly_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/ykalchevskiy/poly"
@@ -43,6 +45,10 @@ type T9 struct{}
 
 func (T9) TypeName() string { return "T9" }
 
+type T10 struct{}
+
+func (T10) TypeName() string { return "T10" }
+
 func ExampleTypeList() {
 	var tl poly.TypeList[T1, poly.TypeList[T2, poly.TypeList[T3, poly.TypeListLast]]]
 
@@ -151,3 +157,105 @@ func TestTypes9(t *testing.T) {
 		}
 	}
 }
+
+func TestConcat2(t *testing.T) {
+	var tl poly.Concat2[poly.Types9[T1, T2, T3, T4, T5, T6, T7, T8, T9], poly.Types1[T10]]
+
+	for i, typ := range tl.Types() {
+		if name := fmt.Sprintf("T%d", i+1); name != typ.Name {
+			t.Errorf("expected %s, got %s", name, typ.Name)
+		}
+	}
+}
+
+func TestConcat9(t *testing.T) {
+	var tl poly.Concat9[
+		poly.Types1[T1], poly.Types1[T2], poly.Types1[T3], poly.Types1[T4], poly.Types1[T5],
+		poly.Types1[T6], poly.Types1[T7], poly.Types1[T8], poly.Types2[T9, T10],
+	]
+
+	for i, typ := range tl.Types() {
+		if name := fmt.Sprintf("T%d", i+1); name != typ.Name {
+			t.Errorf("expected %s, got %s", name, typ.Name)
+		}
+	}
+}
+
+func TestLookupType_Concat(t *testing.T) {
+	type Types = poly.Concat2[poly.Types9[T1, T2, T3, T4, T5, T6, T7, T8, T9], poly.Types1[T10]]
+
+	for i := 1; i <= 10; i++ {
+		name := fmt.Sprintf("T%d", i)
+
+		if typ, ok := poly.LookupType[Types](name); !ok || typ.Name != name {
+			t.Errorf("expected %s, got %v", name, typ)
+		}
+	}
+
+	if _, ok := poly.LookupType[Types]("T11"); ok {
+		t.Errorf("expected T11 not to be found")
+	}
+}
+
+type T1Other struct{}
+
+func (T1Other) TypeName() string { return "T1" }
+
+func TestUnion(t *testing.T) {
+	var tl poly.Union[poly.Types3[T1, T2, T3], poly.Types3[T2, T4, T5]]
+
+	for i, typ := range tl.Types() {
+		if name := fmt.Sprintf("T%d", i+1); name != typ.Name {
+			t.Errorf("expected %s, got %s", name, typ.Name)
+		}
+	}
+
+	if err := poly.CheckTypes[poly.Union[poly.Types3[T1, T2, T3], poly.Types3[T2, T4, T5]]](); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	t.Run("duplicate", func(t *testing.T) {
+		type Duplicate = poly.Union[poly.Types2[T1, T2], poly.Types1[T1Other]]
+
+		const expected = "poly: duplicate TypeName T1 of poly_test.T1 and poly_test.T1Other"
+
+		if err := poly.CheckTypes[Duplicate](); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected duplicate TypeName error, got %v", err)
+		}
+
+		if _, err := json.Marshal(poly.Poly[any, Duplicate]{Value: T2{}}); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q marshaling error, got %v", expected, err)
+		}
+
+		var p poly.Poly[any, Duplicate]
+
+		if err := json.Unmarshal([]byte(`{"type":"T2"}`), &p); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q unmarshaling error, got %v", expected, err)
+		}
+	})
+}
+
+func TestWithout(t *testing.T) {
+	var tl poly.Without[poly.Types5[T1, T2, T3, T4, T5], poly.Types3[T2, T5, T1Other]]
+
+	// T1Other has the same TypeName as T1, but it is another type, so T1 is kept
+	var names []string
+	for _, typ := range tl.Types() {
+		names = append(names, typ.Name)
+	}
+
+	if expected := "T1,T3,T4"; strings.Join(names, ",") != expected {
+		t.Errorf("expected %s, got %v", expected, names)
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	if err := poly.CheckTypes[poly.Concat2[poly.Types2[T1, T2], poly.Types1[T3]]](); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := poly.CheckTypes[poly.Concat2[poly.Types2[T1, T2], poly.Types1[T1Other]]]()
+	if err == nil || !strings.Contains(err.Error(), "poly: duplicate TypeName T1 of poly_test.T1 and poly_test.T1Other") {
+		t.Errorf("expected duplicate TypeName error, got %v", err)
+	}
+}
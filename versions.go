@@ -0,0 +1,152 @@
+package poly
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// Versioned can be implemented by the types to version the schema of their JSON.
+// PolyVersion returns the current version, starting from 1, which is marshaled by Poly
+// in the 'version' member next to the discriminator, so the types must not use this member themselves.
+// When unmarshaling, the JSON of an older version (or without a version, which is treated as version 1)
+// is upgraded with the migrations registered by RegisterMigration before it is decoded.
+// A patch of the current value, i.e. the JSON with its TypeName or without a discriminator, is not migrated,
+// as it may only have some of the members.
+type Versioned interface {
+	PolyVersion() int
+}
+
+type migrationKey struct {
+	reflectType reflect.Type
+	from        int
+}
+
+// migrations is the registry of RegisterMigration, which is global like the registries of encoding/gob.
+var migrations sync.Map //nolint:gochecknoglobals // migrationKey -> func([]byte) ([]byte, error)
+
+// RegisterMigration registers the migration of the JSON of the type V from the version from to the version from+1.
+// The migration receives and returns the JSON object of the value without the 'type' and 'version' members.
+// V and *V share the migrations, so they apply whether the type is listed as a pointer or not.
+// Like gob.Register, it is expected to be called during the initialization, and it panics if the migration
+// is already registered.
+func RegisterMigration[V TypeName](from int, migrate func(data []byte) ([]byte, error)) {
+	key := migrationKey{reflectType: baseType(reflect.TypeOf((*V)(nil)).Elem()), from: from}
+
+	if _, loaded := migrations.LoadOrStore(key, migrate); loaded {
+		panic(fmt.Sprintf("poly: migration of %s from version %d is already registered", key.reflectType, from))
+	}
+}
+
+// baseType returns the non-pointer type of a type listed as a pointer, and the type itself otherwise.
+func baseType(reflectType reflect.Type) reflect.Type {
+	if reflectType.Kind() == reflect.Pointer {
+		return reflectType.Elem()
+	}
+
+	return reflectType
+}
+
+// typeVersion returns the current version of the type, or 0 if it is not Versioned.
+// It is only called by indexTypes, the version is then taken from the typeIndex.
+// A type listed as a pointer is asked via a pointer to its zero value rather than a nil pointer,
+// so PolyVersion can be declared with a value receiver.
+func typeVersion(typ Type) int {
+	zero := reflect.Zero(typ.ReflectType)
+	if typ.ReflectType.Kind() == reflect.Pointer {
+		zero = reflect.New(typ.ReflectType.Elem())
+	}
+
+	versioned, ok := zero.Interface().(Versioned)
+	if !ok {
+		return 0
+	}
+
+	return versioned.PolyVersion()
+}
+
+// jsonDiscriminator returns the JSON members identifying the type: the discriminator and the version if it is Versioned.
+func jsonDiscriminator(typeName string, version int) string {
+	members := `"type":"` + typeName + `"`
+
+	if version > 0 {
+		members += `,"version":` + strconv.Itoa(version)
+	}
+
+	return members
+}
+
+// migrate upgrades the JSON object in data to the current version of the type if it is Versioned.
+// Otherwise, i.e. if current is 0, data is returned as is.
+func migrate(typ Type, current int, data []byte) ([]byte, error) {
+	if current == 0 {
+		return data, nil
+	}
+
+	var members map[string]json.RawMessage
+
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, fmt.Errorf("poly: cannot unmarshal version: %w", err)
+	}
+
+	version := 1
+
+	if versionData, ok := members["version"]; ok {
+		if err := json.Unmarshal(versionData, &version); err != nil {
+			return nil, fmt.Errorf("poly: cannot unmarshal version: %w", err)
+		}
+	}
+
+	delete(members, "type")
+	delete(members, "version")
+
+	return upgrade(typ, current, version, members)
+}
+
+// migrateBody upgrades the JSON object in body without the discriminator and the version, which is carried
+// separately, to the current version of the type if it is Versioned. A zero version is treated as version 1.
+func migrateBody(typ Type, current, version int, body []byte) ([]byte, error) {
+	if current == 0 {
+		return body, nil
+	}
+
+	if version == 0 {
+		version = 1
+	}
+
+	var members map[string]json.RawMessage
+
+	if err := json.Unmarshal(body, &members); err != nil {
+		return nil, fmt.Errorf("poly: cannot migrate %s: %w", typ.Name, err)
+	}
+
+	return upgrade(typ, current, version, members)
+}
+
+// upgrade applies the migrations of the Versioned type from the version to the current one to the members.
+func upgrade(typ Type, current, version int, members map[string]json.RawMessage) ([]byte, error) {
+	if version > current {
+		return nil, fmt.Errorf("poly: unsupported version %d of %s, the current one is %d", version, typ.Name, current)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return nil, fmt.Errorf("poly: cannot migrate %s: %w", typ.Name, err)
+	}
+
+	for ; version < current; version++ {
+		m, ok := migrations.Load(migrationKey{reflectType: baseType(typ.ReflectType), from: version})
+		if !ok {
+			return nil, fmt.Errorf("poly: no migration of %s from version %d", typ.Name, version)
+		}
+
+		data, err = m.(func([]byte) ([]byte, error))(data) //nolint:forcetypeassert
+		if err != nil {
+			return nil, fmt.Errorf("poly: cannot migrate %s from version %d: %w", typ.Name, version, err)
+		}
+	}
+
+	return data, nil
+}
@@ -0,0 +1,271 @@
+package poly_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ykalchevskiy/poly"
+)
+
+// ItemVersioned is at version 3:
+// version 1 had {"name":"n","tag":"t"}, version 2 renamed "name" to "full_name",
+// and version 3 replaced "tag" with "tags".
+type ItemVersioned struct {
+	FullName string   `json:"full_name"`
+	Tags     []string `json:"tags"`
+}
+
+func (ItemVersioned) IsItemValue() {}
+
+func (ItemVersioned) TypeName() string {
+	return "item-versioned"
+}
+
+func (ItemVersioned) PolyVersion() int {
+	return 3
+}
+
+type ItemUnmigrated struct{}
+
+func (ItemUnmigrated) IsItemValue() {}
+
+func (ItemUnmigrated) TypeName() string {
+	return "item-unmigrated"
+}
+
+func (ItemUnmigrated) PolyVersion() int {
+	return 2
+}
+
+func init() {
+	poly.RegisterMigration[ItemVersioned](1, func(data []byte) ([]byte, error) {
+		var v map[string]any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+
+		v["full_name"] = v["name"]
+		delete(v, "name")
+
+		return json.Marshal(v)
+	})
+
+	poly.RegisterMigration[ItemVersioned](2, func(data []byte) ([]byte, error) {
+		var v map[string]any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+
+		if tag, ok := v["tag"]; ok {
+			v["tags"] = []any{tag}
+			delete(v, "tag")
+		}
+
+		return json.Marshal(v)
+	})
+}
+
+type ItemVersionedValue = poly.Poly[IsItemValue, poly.Types2[ItemValue1, ItemVersioned]]
+
+func TestVersioned(t *testing.T) {
+	tests := []struct {
+		name string
+		bIn  string
+	}{
+		{name: "no version", bIn: `{"type":"item-versioned","name":"n","tag":"t"}`},
+		{name: "version 1", bIn: `{"type":"item-versioned","version":1,"name":"n","tag":"t"}`},
+		{name: "version 2", bIn: `{"version":2,"full_name":"n","tag":"t","type":"item-versioned"}`},
+		{name: "version 3", bIn: `{"type":"item-versioned","version":3,"full_name":"n","tags":["t"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var item ItemVersionedValue
+
+			if err := json.Unmarshal([]byte(tt.bIn), &item); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+
+			bOut, err := json.Marshal(item)
+			if err != nil {
+				t.Fatalf("marshaling error: %v", err)
+			}
+
+			if expected := []byte(`{"type":"item-versioned","version":3,"full_name":"n","tags":["t"]}`); !bytes.Equal(expected, bOut) {
+				t.Fatalf("expected %s, got %s", expected, bOut)
+			}
+		})
+	}
+
+	t.Run("zero", func(t *testing.T) {
+		bOut, err := json.Marshal(poly.Poly[IsItemValue, poly.Types1[ItemVersioned]]{Value: ItemVersioned{}})
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if expected := []byte(`{"type":"item-versioned","version":3,"full_name":"","tags":null}`); !bytes.Equal(expected, bOut) {
+			t.Fatalf("expected %s, got %s", expected, bOut)
+		}
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		var item ItemVersionedValue
+
+		err := json.Unmarshal([]byte(`{"type":"item-versioned","version":4}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: unsupported version 4 of item-versioned") {
+			t.Fatalf("expected unsupported version error, got %v", err)
+		}
+	})
+
+	t.Run("missing migration", func(t *testing.T) {
+		var item poly.Poly[IsItemValue, poly.Types1[ItemUnmigrated]]
+
+		err := json.Unmarshal([]byte(`{"type":"item-unmigrated"}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: no migration of item-unmigrated from version 1") {
+			t.Fatalf("expected missing migration error, got %v", err)
+		}
+	})
+
+	t.Run("invalid version", func(t *testing.T) {
+		var item ItemVersionedValue
+
+		err := json.Unmarshal([]byte(`{"type":"item-versioned","version":"1"}`), &item)
+		if err == nil || !strings.Contains(err.Error(), "poly: cannot unmarshal version") {
+			t.Fatalf("expected version error, got %v", err)
+		}
+	})
+}
+
+func TestRegisterMigration(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expected panic for duplicate migration")
+		}
+	}()
+
+	poly.RegisterMigration[ItemVersioned](1, func(data []byte) ([]byte, error) { return data, nil })
+}
+
+func TestVersioned_Body(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		typeName, version, body, err := ItemVersionedValue{Value: ItemVersioned{FullName: "n", Tags: []string{"t"}}}.MarshalBody()
+		if err != nil {
+			t.Fatalf("marshaling error: %v", err)
+		}
+
+		if typeName != "item-versioned" || version != 3 || string(body) != `{"full_name":"n","tags":["t"]}` {
+			t.Fatalf("expected item-versioned, 3 and the body, got %s, %d and %s", typeName, version, body)
+		}
+
+		var item ItemVersionedValue
+
+		if err := item.UnmarshalBody(typeName, version, body); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemVersioned); !ok || got.FullName != "n" || len(got.Tags) != 1 {
+			t.Fatalf("expected ItemVersioned, got %#v", item.Value)
+		}
+	})
+
+	for name, version := range map[string]int{"no version": 0, "version 1": 1} {
+		t.Run(name, func(t *testing.T) {
+			var item ItemVersionedValue
+
+			if err := item.UnmarshalBody("item-versioned", version, []byte(`{"name":"n","tag":"t"}`)); err != nil {
+				t.Fatalf("unmarshaling error: %v", err)
+			}
+
+			if got, ok := item.Value.(ItemVersioned); !ok || got.FullName != "n" || len(got.Tags) != 1 || got.Tags[0] != "t" {
+				t.Fatalf("expected migrated ItemVersioned, got %#v", item.Value)
+			}
+		})
+	}
+
+	t.Run("unsupported version", func(t *testing.T) {
+		var item ItemVersionedValue
+
+		err := item.UnmarshalBody("item-versioned", 4, []byte(`{}`))
+		if err == nil || !strings.Contains(err.Error(), "poly: unsupported version 4 of item-versioned") {
+			t.Fatalf("expected unsupported version error, got %v", err)
+		}
+	})
+}
+
+// ItemPointerVersioned is listed as a pointer, while its methods have value receivers
+// and its migrations are registered for the non-pointer type.
+type ItemPointerVersioned struct {
+	FullName string `json:"full_name"`
+}
+
+func (ItemPointerVersioned) IsItemValue() {}
+
+func (ItemPointerVersioned) TypeName() string {
+	return "item-pointer-versioned"
+}
+
+func (ItemPointerVersioned) PolyVersion() int {
+	return 2
+}
+
+func init() {
+	poly.RegisterMigration[ItemPointerVersioned](1, func(data []byte) ([]byte, error) {
+		var v map[string]any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+
+		v["full_name"] = v["name"]
+		delete(v, "name")
+
+		return json.Marshal(v)
+	})
+}
+
+func TestVersioned_pointer(t *testing.T) {
+	type Item = poly.Poly[IsItemValue, poly.Types1[*ItemPointerVersioned]]
+
+	bOut, err := json.Marshal(Item{Value: &ItemPointerVersioned{FullName: "n"}})
+	if err != nil {
+		t.Fatalf("marshaling error: %v", err)
+	}
+
+	if expected := `{"type":"item-pointer-versioned","version":2,"full_name":"n"}`; string(bOut) != expected {
+		t.Fatalf("expected %s, got %s", expected, bOut)
+	}
+
+	var item Item
+
+	if err := json.Unmarshal([]byte(`{"type":"item-pointer-versioned","name":"n"}`), &item); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if got, ok := item.Value.(*ItemPointerVersioned); !ok || got.FullName != "n" {
+		t.Fatalf("expected migrated *ItemPointerVersioned, got %#v", item.Value)
+	}
+}
+
+func TestVersioned_patch(t *testing.T) {
+	item := ItemVersionedValue{Value: ItemVersioned{FullName: "n", Tags: []string{"t"}}}
+
+	// a patch without a version is not treated as version 1, which would move "name" to "full_name"
+	for _, data := range []string{`{"type":"item-versioned","tags":["t2"]}`, `{"tags":["t2"]}`} {
+		if err := json.Unmarshal([]byte(data), &item); err != nil {
+			t.Fatalf("unmarshaling error: %v", err)
+		}
+
+		if got, ok := item.Value.(ItemVersioned); !ok || got.FullName != "n" || len(got.Tags) != 1 || got.Tags[0] != "t2" {
+			t.Fatalf("expected patched ItemVersioned, got %#v", item.Value)
+		}
+	}
+
+	if err := item.UnmarshalBody("item-versioned", 0, []byte(`{"tags":["t3"]}`)); err != nil {
+		t.Fatalf("unmarshaling error: %v", err)
+	}
+
+	if got, ok := item.Value.(ItemVersioned); !ok || got.FullName != "n" || got.Tags[0] != "t3" {
+		t.Fatalf("expected patched ItemVersioned, got %#v", item.Value)
+	}
+}